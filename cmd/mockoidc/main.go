@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"vapiv/pkg/oidc/mock"
+)
+
+// 本地 OIDC 身份提供方，配合 OIDC_PROVIDERS=mock 使用：
+//
+//	go run ./cmd/mockoidc -addr :9999
+//	OIDC_MOCK_ISSUER=http://localhost:9999
+func main() {
+	addr := flag.String("addr", ":9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer URL seen by clients")
+	clientID := flag.String("client-id", "vapiv", "client id")
+	clientSecret := flag.String("client-secret", "secret", "client secret")
+	sub := flag.String("sub", "mock-user-1", "subject of the signed-in user")
+	email := flag.String("email", "dev@example.com", "email of the signed-in user")
+	name := flag.String("name", "Mock User", "name of the signed-in user")
+	flag.Parse()
+
+	srv, err := mock.New(*issuer, *clientID, *clientSecret, mock.User{
+		Subject:       *sub,
+		Email:         *email,
+		EmailVerified: true,
+		Name:          *name,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("mock OIDC provider listening on %s (issuer %s)", *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
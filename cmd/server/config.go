@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"vapiv/internal/config"
+)
+
+// configCommand 处理 config 子命令，返回进程退出码
+//
+//	server config print [--redacted]   输出合并默认值、配置文件和环境变量后的配置
+//	server config check                只校验配置
+func configCommand(file string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: server [-config file] config print [--redacted] | config check")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	redacted := fs.Bool("redacted", false, "hide passwords, secrets and tokens")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Read(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "print":
+		out := cfg
+		if *redacted {
+			out = cfg.Redacted()
+		}
+		if err := out.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "check":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q\n", args[0])
+		return 2
+	}
+
+	// 输出配置后仍然报告校验错误，便于排查
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"vapiv/internal/config"
 	"vapiv/internal/model"
 	"vapiv/internal/router"
+	"vapiv/internal/server"
+	"vapiv/internal/tracing"
+	"vapiv/pkg/logger"
 
 	_ "vapiv/docs"
 
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -20,30 +30,138 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name X-API-Key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 
 func main() {
 	godotenv.Load()
 
-	cfg := config.Load()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file, environment variables take precedence")
+	flag.Parse()
+	if flag.Arg(0) == "config" {
+		os.Exit(configCommand(*configFile, flag.Args()[1:]))
+	}
+	if flag.NArg() > 0 {
+		log.Fatalf("unknown command %q", flag.Arg(0))
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// server.mode 可以来自配置文件，gin 自己只读取 GIN_MODE 环境变量
+	gin.SetMode(cfg.Server.Mode)
+	if cfg.JWT.Secret == config.DefaultJWTSecret {
+		slog.Warn("JWT_SECRET is the default value, the server will refuse to start in release mode")
+	}
+
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		log.Fatal(err)
+	}
+	appLog, err := logger.New(os.Stdout, level, cfg.Log.Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// log 包的输出也经过 slog，统一为结构化日志
+	slog.SetDefault(appLog)
+
+	// 需要在初始化数据库和 Redis 之前完成，它们的插件使用全局 TracerProvider
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("failed to set up tracing:", err)
+	}
 
 	db, err := config.InitDB(cfg)
 	if err != nil {
 		log.Fatal("failed to connect database:", err)
 	}
 
-	db.AutoMigrate(&model.User{}, &model.APIKey{}, &model.APIUsage{}, &model.APIConfig{})
+	db.AutoMigrate(
+		&model.User{}, &model.APIKey{}, &model.APIUsage{}, &model.APIConfig{}, &model.UserIdentity{},
+		&model.Organization{}, &model.OrgMember{}, &model.OrgInvitation{}, &model.AuditEvent{}, &model.NotificationSetting{}, &model.InviteCode{},
+		&model.Setting{},
+	)
 
 	rdb, err := config.InitRedis(cfg)
+	if err != nil && cfg.Redis.Required {
+		log.Fatal("failed to connect redis:", err)
+	}
 	if err != nil {
-		log.Println("warning: redis not available, rate limiting disabled")
+		slog.Warn("redis not available, rate limiting disabled", "error", err)
 		rdb = nil
 	}
 
-	r := router.Setup(db, rdb, cfg)
+	r, stopApp := router.Setup(db, rdb, cfg)
+
+	srv, err := server.New(server.Config{
+		Addr:              ":" + cfg.Server.Port,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		TLSCertFile:       cfg.Server.TLSCertFile,
+		TLSKeyFile:        cfg.Server.TLSKeyFile,
+	}, r)
+	if err != nil {
+		log.Fatal("failed to configure server:", err)
+	}
+
+	scheme := "http"
+	if srv.TLS() {
+		scheme = "https"
+	}
+	slog.Info("server starting", "port", cfg.Server.Port)
+	slog.Info("swagger", "url", scheme+"://localhost:"+cfg.Server.Port+"/swagger/index.html")
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
 
-	log.Printf("Server starting on port %s", cfg.Server.Port)
-	log.Printf("Swagger: http://localhost:%s/swagger/index.html", cfg.Server.Port)
-	if err := r.Run(":" + cfg.Server.Port); err != nil {
-		log.Fatal("failed to start server:", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			log.Fatal("failed to start server:", err)
+		}
+	case <-ctx.Done():
+	}
+	// 再次收到信号时直接退出
+	stop()
+
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// 先停止接收请求，再停止后台任务，最后关闭连接
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http server shutdown", "error", err)
+	}
+	if err := stopApp(shutdownCtx); err != nil {
+		slog.Error("background tasks shutdown", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("tracing shutdown", "error", err)
+	}
+	if rdb != nil {
+		if err := rdb.Close(); err != nil {
+			slog.Error("close redis", "error", err)
+		}
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("close database", "error", err)
+		}
 	}
+	slog.Info("server stopped")
 }
@@ -15,24 +15,54 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/bilibili/video": {
+        "/admin/audit": {
             "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
                 "tags": [
-                    "内容数据"
+                    "管理"
                 ],
-                "summary": "B站视频信息",
+                "summary": "查询审计日志",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "被操作的用户ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "操作人ID",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "视频BV号",
-                        "name": "bvid",
-                        "in": "query",
-                        "required": true
+                        "description": "事件类型，如 login.failure",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间 RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间 RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数，最多 100",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -42,29 +72,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     }
-                }
-            }
-        },
-        "/api/bilibili/video/url": {
-            "get": {
+                },
                 "security": [
                     {
-                        "ApiKeyAuth": []
+                        "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/admin/endpoints": {
+            "get": {
                 "tags": [
-                    "内容数据"
-                ],
-                "summary": "B站视频下载地址",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "视频BV号",
-                        "name": "bvid",
-                        "in": "query",
-                        "required": true
-                    }
+                    "管理"
                 ],
+                "summary": "接口配置列表",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -72,31 +93,26 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     }
-                }
-            }
-        },
-        "/api/crypto/decrypt": {
-            "post": {
+                },
                 "security": [
                     {
-                        "ApiKeyAuth": []
+                        "BearerAuth": []
                     }
-                ],
-                "consumes": [
-                    "application/json"
-                ],
+                ]
+            },
+            "post": {
                 "tags": [
-                    "核心服务"
+                    "管理"
                 ],
-                "summary": "AES解密",
+                "summary": "新增接口配置",
                 "parameters": [
                     {
-                        "description": "解密请求",
-                        "name": "request",
+                        "description": "接口配置",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CryptoReq"
+                            "$ref": "#/definitions/handler.CreateEndpointReq"
                         }
                     }
                 ],
@@ -107,31 +123,57 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/crypto/encrypt": {
-            "post": {
+        "/admin/endpoints/stale": {
+            "get": {
+                "description": "数据库中存在但路由已不存在的配置",
+                "tags": [
+                    "管理"
+                ],
+                "summary": "失效的接口配置",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
                 "security": [
                     {
-                        "ApiKeyAuth": []
+                        "BearerAuth": []
                     }
-                ],
-                "consumes": [
-                    "application/json"
-                ],
+                ]
+            }
+        },
+        "/admin/endpoints/{id}": {
+            "put": {
                 "tags": [
-                    "核心服务"
+                    "管理"
                 ],
-                "summary": "AES加密",
+                "summary": "修改接口配置",
                 "parameters": [
                     {
-                        "description": "加密请求",
-                        "name": "request",
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "需要修改的字段",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CryptoReq"
+                            "$ref": "#/definitions/handler.UpdateEndpointReq"
                         }
                     }
                 ],
@@ -142,31 +184,37 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     }
-                }
-            }
-        },
-        "/api/douyin/video": {
-            "get": {
+                },
                 "security": [
                     {
-                        "ApiKeyAuth": []
+                        "BearerAuth": []
                     }
-                ],
-                "description": "通过抖音分享链接获取视频信息和无水印下载地址",
-                "consumes": [
-                    "application/json"
-                ],
+                ]
+            }
+        },
+        "/admin/endpoints/{id}/status": {
+            "put": {
+                "description": "status=1 上线，status=0 下线，下线后调用方会收到 endpoint offline",
                 "tags": [
-                    "核心服务"
+                    "管理"
                 ],
-                "summary": "解析抖音视频",
+                "summary": "上线/下线接口",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "抖音分享链接",
-                        "name": "url",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "状态",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.EndpointStatusReq"
+                        }
                     }
                 ],
                 "responses": {
@@ -176,78 +224,97 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/ip": {
+        "/admin/health": {
             "get": {
+                "description": "依赖检查结果（包括错误详情），以及根据最近 10 分钟调用成功率估计的第三方服务可达性",
                 "tags": [
-                    "核心服务"
-                ],
-                "summary": "IP地址查询",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "IP地址",
-                        "name": "ip",
-                        "in": "query"
-                    }
+                    "管理"
                 ],
+                "summary": "服务状态详情",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/health.Detail"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/qq/avatar": {
+        "/admin/invite-codes": {
             "get": {
                 "tags": [
-                    "内容数据"
-                ],
-                "summary": "QQ头像获取",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "QQ号",
-                        "name": "qq",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "头像尺寸",
-                        "name": "size",
-                        "in": "query"
-                    }
+                    "管理"
                 ],
+                "summary": "邀请码列表",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/model.InviteCode"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
-                }
-            }
-        },
-        "/auth/login": {
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
             "post": {
+                "description": "注册模式为 invite 时使用，max_uses 为 0 表示不限次数，expires_in_hours 为 0 表示不过期",
                 "tags": [
-                    "认证"
+                    "管理"
                 ],
-                "summary": "用户登录",
+                "summary": "生成邀请码",
                 "parameters": [
                     {
-                        "description": "登录信息",
+                        "description": "使用次数、有效期和备注",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.LoginReq"
+                            "$ref": "#/definitions/handler.CreateInviteCodeReq"
                         }
                     }
                 ],
@@ -255,27 +322,42 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.InviteCode"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/auth/register": {
-            "post": {
+        "/admin/invite-codes/{id}": {
+            "delete": {
                 "tags": [
-                    "认证"
+                    "管理"
                 ],
-                "summary": "用户注册",
+                "summary": "作废邀请码",
                 "parameters": [
                     {
-                        "description": "注册信息",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.RegisterReq"
-                        }
+                        "type": "integer",
+                        "description": "邀请码ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -285,50 +367,73 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/auth/reset-password": {
-            "post": {
+        "/admin/settings": {
+            "get": {
+                "description": "全部可在运行时修改的设置，包括类型、默认值、当前值和最后修改人",
                 "tags": [
-                    "认证"
-                ],
-                "summary": "重置密码",
-                "parameters": [
-                    {
-                        "description": "请求参数",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.ResetPasswordReq"
-                        }
-                    }
+                    "管理"
                 ],
+                "summary": "运行时设置",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/settings.Entry"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/auth/send-code": {
-            "post": {
+        "/admin/settings/{key}": {
+            "put": {
+                "description": "立即对所有实例生效，修改记入审计日志",
                 "tags": [
-                    "认证"
+                    "管理"
                 ],
-                "summary": "发送验证码",
+                "summary": "修改运行时设置",
                 "parameters": [
                     {
-                        "description": "请求参数",
+                        "type": "string",
+                        "description": "设置 key，如 ratelimit.requests",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "新值",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.SendCodeReq"
+                            "$ref": "#/definitions/handler.SetSettingReq"
                         }
                     }
                 ],
@@ -336,14 +441,1803 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/settings.Entry"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
-                }
-            }
-        }
-    },
-    "definitions": {
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "tags": [
+                    "管理"
+                ],
+                "summary": "恢复运行时设置的默认值",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设置 key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/settings.Entry"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/users/{id}/impersonate": {
+            "post": {
+                "description": "签发代表该用户的短期 token，默认只读，不能修改密码、邮箱、注销账号或查看计费；期间的每个请求都会写入审计日志",
+                "tags": [
+                    "管理"
+                ],
+                "summary": "模拟用户登录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "有效期、是否允许写操作和原因",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ImpersonateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/bilibili/video": {
+            "get": {
+                "tags": [
+                    "内容数据"
+                ],
+                "summary": "B站视频信息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "视频BV号",
+                        "name": "bvid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/bilibili/video/url": {
+            "get": {
+                "tags": [
+                    "内容数据"
+                ],
+                "summary": "B站视频下载地址",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "视频BV号",
+                        "name": "bvid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/crypto/decrypt": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "核心服务"
+                ],
+                "summary": "AES解密",
+                "parameters": [
+                    {
+                        "description": "解密请求",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CryptoReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/crypto/encrypt": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "核心服务"
+                ],
+                "summary": "AES加密",
+                "parameters": [
+                    {
+                        "description": "加密请求",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CryptoReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/douyin/video": {
+            "get": {
+                "description": "通过抖音分享链接获取视频信息和无水印下载地址",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "核心服务"
+                ],
+                "summary": "解析抖音视频",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "抖音分享链接",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/ip": {
+            "get": {
+                "tags": [
+                    "核心服务"
+                ],
+                "summary": "IP地址查询",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IP地址",
+                        "name": "ip",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/qq/avatar": {
+            "get": {
+                "tags": [
+                    "内容数据"
+                ],
+                "summary": "QQ头像获取",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "QQ号",
+                        "name": "qq",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "头像尺寸",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/challenge": {
+            "get": {
+                "description": "工作量证明：找到 nonce 使 sha256(prefix + nonce) 至少有 difficulty 个前导零比特，发送验证码时提交 \"\u003cid\u003e.\u003cnonce\u003e\"。同一 IP 请求越频繁难度越高",
+                "tags": [
+                    "认证"
+                ],
+                "summary": "获取人机验证题目",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/captcha.Challenge"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "username 可以填写用户名或邮箱",
+                "tags": [
+                    "认证"
+                ],
+                "summary": "用户登录",
+                "parameters": [
+                    {
+                        "description": "登录信息",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LoginReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/providers": {
+            "get": {
+                "tags": [
+                    "认证"
+                ],
+                "summary": "可用的外部登录方式",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/{provider}/callback": {
+            "get": {
+                "tags": [
+                    "认证"
+                ],
+                "summary": "外部身份登录回调",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "提供方名称",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "授权码",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/{provider}/login": {
+            "get": {
+                "description": "跳转到身份提供方授权（authorization code + PKCE）",
+                "tags": [
+                    "认证"
+                ],
+                "summary": "外部身份登录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "提供方名称",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "受注册模式限制：closed 不开放注册，invite 需要邀请码，domain 只允许白名单域名的邮箱",
+                "tags": [
+                    "认证"
+                ],
+                "summary": "用户注册",
+                "parameters": [
+                    {
+                        "description": "注册信息",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RegisterReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/registration": {
+            "get": {
+                "description": "open、closed、invite 或 domain",
+                "tags": [
+                    "认证"
+                ],
+                "summary": "注册模式",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reset-password": {
+            "post": {
+                "tags": [
+                    "认证"
+                ],
+                "summary": "重置密码",
+                "parameters": [
+                    {
+                        "description": "请求参数",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ResetPasswordReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/send-code": {
+            "post": {
+                "description": "需要先完成 GET /auth/challenge 的人机验证",
+                "tags": [
+                    "认证"
+                ],
+                "summary": "发送验证码",
+                "parameters": [
+                    {
+                        "description": "请求参数",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.SendCodeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/catalog": {
+            "get": {
+                "description": "所有已上线接口的名称、价格、认证方式、限流等级和参数说明，支持 ETag 缓存",
+                "tags": [
+                    "公共"
+                ],
+                "summary": "接口目录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "上次返回的 ETag",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    }
+                }
+            }
+        },
+        "/catalog/errors": {
+            "get": {
+                "description": "失败响应的 error 字段取值、对应的 HTTP 状态码和说明（按请求语言）。旧客户端可以在请求头带上 X-Legacy-Status: 1，错误响应仍返回 HTTP 200（400 和 401 除外）",
+                "tags": [
+                    "公共"
+                ],
+                "summary": "错误码目录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "zh-CN 或 en，默认按 Accept-Language",
+                        "name": "lang",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/response.ErrorCode"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/dev/mail": {
+            "get": {
+                "description": "仅 EMAIL_BACKEND=memory 时可用，按时间倒序",
+                "tags": [
+                    "开发"
+                ],
+                "summary": "内存收件箱",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "收件人",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "开发"
+                ],
+                "summary": "清空内存收件箱",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/dev/mail/{id}": {
+            "get": {
+                "tags": [
+                    "开发"
+                ],
+                "summary": "内存收件箱中的邮件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "邮件ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "进程能处理请求即返回 200，不检查数据库和 Redis，用于 liveness probe",
+                "tags": [
+                    "公共"
+                ],
+                "summary": "存活检查",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs": {
+            "get": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "我所在的组织",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "创建组织",
+                "parameters": [
+                    {
+                        "description": "组织信息",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateOrgReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}": {
+            "get": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "组织详情（含余额）",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/apikeys": {
+            "get": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "组织 API Key 列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Key 从组织余额扣费，调用记录同时归属到创建者",
+                "tags": [
+                    "组织"
+                ],
+                "summary": "创建组织 API Key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key 名称",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/apikeys/{key_id}": {
+            "delete": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "删除组织 API Key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Key ID",
+                        "name": "key_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/apikeys/{key_id}/rotate": {
+            "post": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "轮换组织 API Key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Key ID",
+                        "name": "key_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/invitations": {
+            "post": {
+                "description": "通过邮件发送邀请码，owner/admin 可用",
+                "tags": [
+                    "组织"
+                ],
+                "summary": "邀请成员",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "邀请信息",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.InviteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/members": {
+            "get": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "组织成员列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/members/{user_id}": {
+            "put": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "修改成员角色",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "用户ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "角色",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateMemberReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "移除成员或退出组织",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "用户ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/orgs/{id}/usage": {
+            "get": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "组织调用记录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "检查数据库和 Redis。必需依赖不可用时返回 503；只有可选的 Redis 不可用时返回 200，status 为 degraded。错误详情见 /admin/health",
+                "tags": [
+                    "公共"
+                ],
+                "summary": "就绪检查",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/apikey/{id}/rotate": {
+            "post": {
+                "description": "生成新的 Key 值，旧值立即失效",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "轮换 API Key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/audit": {
+            "get": {
+                "description": "登录、API Key、密码、邮箱等敏感操作记录",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "我的安全日志",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数，最多 100",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/delete": {
+            "post": {
+                "description": "需要密码和邮箱验证码；吊销所有 Key、匿名化调用记录，数据在保留期后彻底删除",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "注销账号",
+                "parameters": [
+                    {
+                        "description": "密码和验证码",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DeleteAccountReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/delete/send-code": {
+            "post": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "发送注销账号验证码",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/email": {
+            "put": {
+                "description": "校验新邮箱收到的验证码后更换，并通知旧邮箱",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "更换邮箱",
+                "parameters": [
+                    {
+                        "description": "新邮箱和验证码",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChangeEmailReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/email/send-code": {
+            "post": {
+                "description": "验证码发送到新邮箱",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "发送更换邮箱验证码",
+                "parameters": [
+                    {
+                        "description": "新邮箱",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChangeEmailCodeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/export": {
+            "get": {
+                "description": "包含资料、API Key（仅前缀）、调用记录和组织成员关系",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "导出个人数据",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "zip"
+                        ],
+                        "type": "string",
+                        "description": "json 或 zip",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ExportData"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/identities": {
+            "get": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "已关联的外部身份",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/identities/{provider}": {
+            "delete": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "取消关联外部身份",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "提供方名称",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/identities/{provider}/link": {
+            "post": {
+                "description": "返回授权地址，浏览器访问后在回调中关联到当前账号",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "关联外部身份",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "提供方名称",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/invitations/accept": {
+            "post": {
+                "tags": [
+                    "组织"
+                ],
+                "summary": "接受组织邀请",
+                "parameters": [
+                    {
+                        "description": "邀请码",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.AcceptInvitationReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/locale": {
+            "put": {
+                "description": "邮件和通知按该语言发送",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "设置语言",
+                "parameters": [
+                    {
+                        "description": "语言",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LocaleReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/notifications": {
+            "get": {
+                "description": "返回可选的事件、已启用的渠道和当前设置；没有设置的事件默认发送到账号邮箱",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "通知设置",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "put": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "新增或修改通知设置",
+                "parameters": [
+                    {
+                        "description": "事件和渠道",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.NotificationSettingReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/notifications/{id}": {
+            "delete": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "删除通知设置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/notifications/{id}/test": {
+            "post": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "发送测试通知",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/user/password": {
+            "put": {
+                "tags": [
+                    "用户"
+                ],
+                "summary": "修改密码",
+                "parameters": [
+                    {
+                        "description": "当前密码和新密码",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChangePasswordReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        }
+    },
+    "definitions": {
+        "captcha.Challenge": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.AcceptInvitationReq": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ChangeEmailCodeReq": {
+            "type": "object",
+            "required": [
+                "new_email"
+            ],
+            "properties": {
+                "new_email": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ChangeEmailReq": {
+            "type": "object",
+            "required": [
+                "code",
+                "new_email"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "new_email": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ChangePasswordReq": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "old_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.CreateEndpointReq": {
+            "type": "object",
+            "required": [
+                "endpoint",
+                "method",
+                "name"
+            ],
+            "properties": {
+                "cost": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "is_public": {
+                    "type": "boolean"
+                },
+                "method": {
+                    "type": "string",
+                    "enum": [
+                        "GET",
+                        "POST",
+                        "PUT",
+                        "PATCH",
+                        "DELETE"
+                    ]
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rate_tier": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "standard",
+                        "high"
+                    ]
+                },
+                "status": {
+                    "type": "integer",
+                    "enum": [
+                        0,
+                        1
+                    ]
+                }
+            }
+        },
+        "handler.CreateInviteCodeReq": {
+            "type": "object",
+            "properties": {
+                "expires_in_hours": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_uses": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "note": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "handler.CreateOrgReq": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
         "handler.CryptoReq": {
             "type": "object",
             "required": [
@@ -359,6 +2253,93 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.DeleteAccountReq": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.EndpointStatusReq": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "type": "integer",
+                    "enum": [
+                        0,
+                        1
+                    ]
+                }
+            }
+        },
+        "handler.ImpersonateReq": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "ttl_minutes": {
+                    "description": "有效期（分钟），默认 15，最长 60",
+                    "type": "integer",
+                    "maximum": 60,
+                    "minimum": 1
+                },
+                "write": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handler.InviteReq": {
+            "type": "object",
+            "required": [
+                "email",
+                "role"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "admin",
+                        "developer",
+                        "billing"
+                    ]
+                }
+            }
+        },
+        "handler.LocaleReq": {
+            "type": "object",
+            "required": [
+                "locale"
+            ],
+            "properties": {
+                "locale": {
+                    "type": "string",
+                    "enum": [
+                        "zh-CN",
+                        "en"
+                    ]
+                }
+            }
+        },
         "handler.LoginReq": {
             "type": "object",
             "required": [
@@ -369,8 +2350,43 @@ const docTemplate = `{
                 "password": {
                     "type": "string"
                 },
-                "username": {
-                    "type": "string"
+                "username": {
+                    "description": "用户名或邮箱",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.NotificationSettingReq": {
+            "type": "object",
+            "required": [
+                "channel",
+                "event"
+            ],
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event": {
+                    "type": "string",
+                    "enum": [
+                        "verification",
+                        "low_balance",
+                        "key_expiry",
+                        "security"
+                    ]
+                },
+                "secret": {
+                    "description": "webhook 签名密钥或机器人加签密钥，为空时保留原值",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "target": {
+                    "description": "邮箱、手机号或 webhook 地址；email 渠道为空时发送到账号邮箱",
+                    "type": "string",
+                    "maxLength": 500
                 }
             }
         },
@@ -389,6 +2405,10 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "invite_code": {
+                    "description": "注册模式为 invite 时必填",
+                    "type": "string"
+                },
                 "password": {
                     "type": "string",
                     "minLength": 6
@@ -421,10 +2441,15 @@ const docTemplate = `{
         "handler.SendCodeReq": {
             "type": "object",
             "required": [
+                "challenge",
                 "email",
                 "purpose"
             ],
             "properties": {
+                "challenge": {
+                    "description": "工作量证明解答，格式为 \"\u003cchallenge id\u003e.\u003cnonce\u003e\"，见 GET /auth/challenge",
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
@@ -437,17 +2462,397 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.SetSettingReq": {
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "value": {
+                    "description": "字符串形式的值，如 200、30s、false",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.UpdateEndpointReq": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "is_public": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rate_tier": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "standard",
+                        "high"
+                    ]
+                },
+                "status": {
+                    "type": "integer",
+                    "enum": [
+                        0,
+                        1
+                    ]
+                }
+            }
+        },
+        "handler.UpdateMemberReq": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "admin",
+                        "developer",
+                        "billing"
+                    ]
+                }
+            }
+        },
+        "health.Check": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "required": {
+                    "description": "是否影响就绪状态，可选依赖不可用时整体为 degraded",
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Detail": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.Check"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "upstreams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/upstream.Reachability"
+                    }
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.Check"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.APIUsage": {
+            "type": "object",
+            "properties": {
+                "api_key_id": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "org_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.InviteCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "max_uses": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "uses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.OrgMember": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "org_id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.User": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UserIdentity": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "response.ErrorCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "message_key": {
+                    "description": "locales 中的消息 key，Message 是默认语言的说明",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
         "response.Response": {
             "type": "object",
             "properties": {
                 "code": {
+                    "description": "成功为 0，失败时为 HTTP 状态码，保留给旧客户端使用",
                     "type": "integer"
                 },
                 "data": {},
+                "error": {
+                    "description": "错误目录中的错误码，见 GET /catalog/errors",
+                    "type": "string"
+                },
                 "message": {
                     "type": "string"
                 }
             }
+        },
+        "settings.Entry": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "upstream.Reachability": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "last_failure": {
+                    "type": "string"
+                },
+                "last_success": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "success_rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "user.ExportData": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/user.ExportedKey"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "identities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.UserIdentity"
+                    }
+                },
+                "memberships": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OrgMember"
+                    }
+                },
+                "profile": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "usage": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.APIUsage"
+                    }
+                }
+            }
+        },
+        "user.ExportedKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org_id": {
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -455,6 +2860,11 @@ const docTemplate = `{
             "type": "apiKey",
             "name": "X-API-Key",
             "in": "header"
+        },
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
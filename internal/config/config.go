@@ -1,98 +1,151 @@
 package config
 
-import (
-	"os"
-	"strconv"
-)
+import "time"
 
+// 每个字段的 yaml 标签是配置文件中的 key，env 是覆盖它的环境变量，default 是默认值，
+// secret 字段在 config print --redacted 中隐藏。新增配置时在 Validate 中补充校验
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	SMTP     SMTPConfig
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Redis    RedisConfig    `yaml:"redis"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	SMTP     SMTPConfig     `yaml:"smtp"`
+	Account  AccountConfig  `yaml:"account"`
+	OIDC     OIDCConfig     `yaml:"oidc"`
+	Notify   NotifyConfig   `yaml:"notify"`
+	Log      LogConfig      `yaml:"log"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing"`
 }
 
 type ServerConfig struct {
-	Port string
-	Mode string
+	Port string `yaml:"port" env:"SERVER_PORT" default:"8080"`
+	// debug、release 或 test
+	Mode string `yaml:"mode" env:"GIN_MODE" default:"debug"`
+	// 错误响应一律返回 HTTP 200，兼容旧客户端
+	LegacyStatus bool `yaml:"legacy_status" env:"API_LEGACY_STATUS" default:"false"`
+
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"30s"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" default:"10s"`
+	// 包括调用第三方服务的时间，不应小于上游请求的超时
+	WriteTimeout   time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"60s"`
+	IdleTimeout    time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" default:"120s"`
+	MaxHeaderBytes int           `yaml:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES" default:"1048576"`
+	// 收到 SIGTERM 后等待进行中请求和后台任务的最长时间
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" default:"30s"`
+	// 同时设置时使用 HTTPS，证书文件更新后自动重新加载
+	TLSCertFile string `yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+	// 可信的反向代理（IP 或 CIDR），只有来自这些地址的 X-Forwarded-For 才会被采用；
+	// 为空时客户端 IP 一律取连接地址
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port     string `yaml:"port" env:"DB_PORT" default:"5432"`
+	User     string `yaml:"user" env:"DB_USER" default:"postgres"`
+	Password string `yaml:"password" env:"DB_PASSWORD" default:"postgres" secret:"true"`
+	DBName   string `yaml:"name" env:"DB_NAME" default:"vapiv"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
 }
 
 type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
-	DB       int
+	Host     string `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Port     string `yaml:"port" env:"REDIS_PORT" default:"6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB" default:"0"`
+	// 为 true 时 Redis 不可用会导致启动失败、/readyz 返回 503；否则只降级运行
+	Required bool `yaml:"required" env:"REDIS_REQUIRED" default:"false"`
 }
 
 type JWTConfig struct {
-	Secret     string
-	ExpireHour int
+	// 用于签名 OIDC state 等服务端内部数据，登录 token 使用下面的密钥对。
+	// release 模式下不能使用默认值，且至少 32 个字符
+	Secret     string `yaml:"secret" env:"JWT_SECRET" default:"your-secret-key-change-in-production" secret:"true"`
+	ExpireHour int    `yaml:"expire_hour" env:"JWT_EXPIRE_HOUR" default:"24"`
+	// 当前签名私钥（RSA 或 Ed25519，PEM），release 模式下必填；
+	// 其他模式为空时每次启动生成临时密钥
+	PrivateKeyFile string `yaml:"private_key_file" env:"JWT_PRIVATE_KEY_FILE"`
+	// 轮换期间仍需验证的旧公钥
+	PublicKeyFiles []string `yaml:"public_key_files" env:"JWT_PUBLIC_KEY_FILES"`
+	Issuer         string   `yaml:"issuer" env:"JWT_ISSUER" default:"vapiv"`
+	Audience       string   `yaml:"audience" env:"JWT_AUDIENCE" default:"vapiv-api"`
 }
 
 type SMTPConfig struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	From     string
+	// smtp、file（写入 CaptureDir）或 memory（内存收件箱，开放 /dev/mail 接口）
+	Backend  string `yaml:"backend" env:"EMAIL_BACKEND" default:"smtp"`
+	Host     string `yaml:"host" env:"SMTP_HOST" default:"smtp.gmail.com"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+	// starttls、tls（465 隐式 TLS）或 none
+	Security string `yaml:"security" env:"SMTP_SECURITY" default:"starttls"`
+	// plain、login、cram-md5 或 none，为空时按是否有用户名自动选择
+	Auth       string `yaml:"auth" env:"SMTP_AUTH"`
+	PoolSize   int    `yaml:"pool_size" env:"SMTP_POOL_SIZE" default:"2"`
+	CaptureDir string `yaml:"capture_dir" env:"EMAIL_CAPTURE_DIR" default:"./mail"`
+}
+
+type AccountConfig struct {
+	// 注销账号后保留数据的天数，过期后彻底删除
+	RetentionDays int `yaml:"retention_days" env:"ACCOUNT_RETENTION_DAYS" default:"30"`
+	// open、closed、invite（需要邀请码）或 domain（只允许 RegistrationDomains 中的邮箱域名）
+	RegistrationMode    string   `yaml:"registration_mode" env:"REGISTRATION_MODE" default:"open"`
+	RegistrationDomains []string `yaml:"registration_domains" env:"REGISTRATION_DOMAINS"`
+}
+
+type OIDCConfig struct {
+	// 环境变量中使用 OIDC_PROVIDERS 和 OIDC_<NAME>_ 前缀，设置后替换配置文件中的列表
+	Providers []OIDCProviderConfig `yaml:"providers"`
+	// 登录成功后跳转的前端地址，token 放在 URL fragment 中；为空时直接返回 JSON
+	SuccessURL string `yaml:"success_url" env:"OIDC_SUCCESS_URL"`
+}
+
+type NotifyConfig struct {
+	// 短信服务商，目前只有 mock；为空时不启用短信渠道
+	SMSProvider string `yaml:"sms_provider" env:"SMS_PROVIDER"`
+	// 余额从阈值以上降到阈值以下时发送 low_balance 通知，0 表示不提醒。
+	// 这是运行时设置 billing.low_balance_threshold 的默认值，可以在 /admin/settings 中修改
+	LowBalanceThreshold int64 `yaml:"low_balance_threshold" env:"LOW_BALANCE_THRESHOLD" default:"100"`
+}
+
+type LogConfig struct {
+	// debug、info、warn 或 error
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	// json 或 text
+	Format string `yaml:"format" env:"LOG_FORMAT" default:"json"`
+	// GORM 日志级别：silent、error、warn 或 info（记录全部 SQL）
+	SQLLevel string `yaml:"sql_level" env:"DB_LOG_LEVEL" default:"warn"`
+	// 超过该时长的 SQL 记为慢查询，0 表示不记录
+	SlowQueryMs int `yaml:"slow_query_ms" env:"DB_SLOW_QUERY_MS" default:"200"`
 }
 
-func Load() *Config {
-	return &Config{
-		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Mode: getEnv("GIN_MODE", "debug"),
-		},
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "vapiv"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
-		},
-		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnv("REDIS_PORT", "6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvInt("REDIS_DB", 0),
-		},
-		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-			ExpireHour: getEnvInt("JWT_EXPIRE_HOUR", 24),
-		},
-		SMTP: SMTPConfig{
-			Host:     getEnv("SMTP_HOST", "smtp.gmail.com"),
-			Port:     getEnvInt("SMTP_PORT", 587),
-			Username: getEnv("SMTP_USERNAME", ""),
-			Password: getEnv("SMTP_PASSWORD", ""),
-			From:     getEnv("SMTP_FROM", ""),
-		},
-	}
+type MetricsConfig struct {
+	// 不为空时 /metrics 只在这个地址（如 127.0.0.1:9090）上提供，不挂在主端口
+	Addr string `yaml:"addr" env:"METRICS_ADDR"`
+	// 不为空时抓取需要 Authorization: Bearer <token>
+	Token string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+type TracingConfig struct {
+	// off、stdout 或 otlp
+	Exporter    string `yaml:"exporter" env:"TRACING_EXPORTER" default:"off"`
+	ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME" default:"vapiv"`
+	// OTLP HTTP 地址，如 localhost:4318，为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint string `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	Insecure bool   `yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE" default:"false"`
+	// 没有上游 trace 时的采样比例
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1"`
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			return intVal
-		}
-	}
-	return defaultValue
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret" secret:"true"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
 }
@@ -2,10 +2,14 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"time"
+
+	"vapiv/pkg/logger"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/plugin/opentelemetry/tracing"
 )
 
 func InitDB(cfg *Config) (*gorm.DB, error) {
@@ -19,12 +23,22 @@ func InitDB(cfg *Config) (*gorm.DB, error) {
 		cfg.Database.SSLMode,
 	)
 
+	level, err := logger.ParseGormLevel(cfg.Log.SQLLevel)
+	if err != nil {
+		return nil, err
+	}
+	slow := time.Duration(cfg.Log.SlowQueryMs) * time.Millisecond
+
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.NewGorm(slog.Default(), level, slow),
 	})
 	if err != nil {
 		return nil, err
 	}
+	// 只有通过 WithContext 传入请求 context 的查询才会挂在请求的 trace 下
+	if err := db.Use(tracing.NewPlugin(tracing.WithoutMetrics(), tracing.WithoutQueryVariables())); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError 指出出错的配置项。Key 是配置文件中的 key，Source 是值的来源：
+// 环境变量名或配置文件路径
+type FieldError struct {
+	Key    string
+	Source string
+	Msg    string
+}
+
+func (e FieldError) Error() string {
+	if e.Source == "" {
+		return e.Key + ": " + e.Msg
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Key, e.Source, e.Msg)
+}
+
+// FieldErrors 是一次加载或校验中发现的全部错误
+type FieldErrors []FieldError
+
+func (errs FieldErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = "  " + e.Error()
+	}
+	return "invalid configuration:\n" + strings.Join(lines, "\n")
+}
+
+// field 是一个可以通过配置文件和环境变量设置的配置项
+type field struct {
+	key   string
+	env   string
+	def   string
+	value reflect.Value
+}
+
+// Load 读取配置并校验，见 Read
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read 依次应用默认值、配置文件和环境变量，后者覆盖前者，不做校验。
+// path 为空时只使用环境变量；.yaml、.yml 按 YAML 解析，.toml 按 TOML 解析，两者的 key 相同。
+// 值为空的环境变量视为未设置
+func Read(path string) (*Config, error) {
+	cfg := &Config{}
+	fields := collect(cfg)
+
+	var errs FieldErrors
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setString(f.value, f.def); err != nil {
+			errs = append(errs, FieldError{Key: f.key, Source: "default", Msg: err.Error()})
+		}
+	}
+	if path != "" {
+		errs = append(errs, readFile(cfg, fields, path)...)
+	}
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if raw := os.Getenv(f.env); raw != "" {
+			if err := setString(f.value, raw); err != nil {
+				errs = append(errs, FieldError{Key: f.key, Source: f.env, Msg: err.Error()})
+			}
+		}
+	}
+	if len(getEnvList("OIDC_PROVIDERS")) > 0 {
+		cfg.OIDC.Providers = loadOIDCProviders()
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// collect 列出 Config 中所有分组下的配置项，OIDC 提供方列表单独处理
+func collect(cfg *Config) []field {
+	var fields []field
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := yamlKey(root.Type().Field(i))
+		sv := root.Field(i)
+		for j := 0; j < sv.NumField(); j++ {
+			sf := sv.Type().Field(j)
+			if sf.Type.Kind() == reflect.Slice && sf.Type.Elem().Kind() == reflect.Struct {
+				continue
+			}
+			fields = append(fields, field{
+				key:   section + "." + yamlKey(sf),
+				env:   sf.Tag.Get("env"),
+				def:   sf.Tag.Get("default"),
+				value: sv.Field(j),
+			})
+		}
+	}
+	return fields
+}
+
+func yamlKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return name
+}
+
+// readFile 解析配置文件并覆盖对应的配置项，未知的 key 视为错误
+func readFile(cfg *Config, fields []field, path string) FieldErrors {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FieldErrors{{Key: "(file)", Source: path, Msg: err.Error()}}
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return FieldErrors{{Key: "(file)", Source: path, Msg: "unsupported config file type, use .yaml, .yml or .toml"}}
+	}
+	if err != nil {
+		return FieldErrors{{Key: "(file)", Source: path, Msg: err.Error()}}
+	}
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+
+	var errs FieldErrors
+	for _, section := range sortedKeys(doc) {
+		values, ok := doc[section].(map[string]interface{})
+		if !ok {
+			errs = append(errs, FieldError{Key: section, Source: path, Msg: "unknown section or not a table"})
+			continue
+		}
+		for _, name := range sortedKeys(values) {
+			key := section + "." + name
+			if key == "oidc.providers" {
+				errs = append(errs, readProviders(cfg, values[name], path)...)
+				continue
+			}
+			f, ok := byKey[key]
+			if !ok {
+				errs = append(errs, FieldError{Key: key, Source: path, Msg: "unknown key"})
+				continue
+			}
+			if err := setValue(f.value, values[name]); err != nil {
+				errs = append(errs, FieldError{Key: key, Source: path, Msg: err.Error()})
+			}
+		}
+	}
+	return errs
+}
+
+func readProviders(cfg *Config, raw interface{}, path string) FieldErrors {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return FieldErrors{{Key: "oidc.providers", Source: path, Msg: "expected a list"}}
+	}
+
+	var errs FieldErrors
+	providers := make([]OIDCProviderConfig, len(list))
+	for i, item := range list {
+		prefix := fmt.Sprintf("oidc.providers[%d]", i)
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			errs = append(errs, FieldError{Key: prefix, Source: path, Msg: "expected a table"})
+			continue
+		}
+		pv := reflect.ValueOf(&providers[i]).Elem()
+		for _, name := range sortedKeys(values) {
+			idx := -1
+			for j := 0; j < pv.NumField(); j++ {
+				if yamlKey(pv.Type().Field(j)) == name {
+					idx = j
+				}
+			}
+			if idx < 0 {
+				errs = append(errs, FieldError{Key: prefix + "." + name, Source: path, Msg: "unknown key"})
+				continue
+			}
+			if err := setValue(pv.Field(idx), values[name]); err != nil {
+				errs = append(errs, FieldError{Key: prefix + "." + name, Source: path, Msg: err.Error()})
+			}
+		}
+	}
+	cfg.OIDC.Providers = providers
+	return errs
+}
+
+// setValue 设置配置文件中的值。文件中的值先转为字符串，与环境变量使用同样的解析规则
+func setValue(v reflect.Value, raw interface{}) error {
+	switch raw := raw.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("expected a single value, got a list")
+		}
+		list := make([]string, len(raw))
+		for i, item := range raw {
+			if _, ok := item.(map[string]interface{}); ok {
+				return fmt.Errorf("expected a list of strings")
+			}
+			list[i] = fmt.Sprint(item)
+		}
+		v.Set(reflect.ValueOf(list))
+		return nil
+	case map[string]interface{}:
+		return fmt.Errorf("expected a single value, got a table")
+	default:
+		return setString(v, fmt.Sprint(raw))
+	}
+}
+
+// setString 按字段类型解析字符串，列表使用逗号分隔
+func setString(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use a value like 30s or 5m", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		v.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadOIDCProviders 读取 OIDC_PROVIDERS 列出的提供方，
+// 每个提供方的配置使用 OIDC_<NAME>_ 前缀
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvList("OIDC_PROVIDERS") {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       getEnvList(prefix + "SCOPES"),
+		})
+	}
+	return providers
+}
+
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
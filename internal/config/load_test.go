@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// clearEnv 清空所有配置项对应的环境变量，值为空的环境变量视为未设置
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, f := range collect(&Config{}) {
+		if f.env != "" {
+			t.Setenv(f.env, "")
+		}
+	}
+	t.Setenv("OIDC_PROVIDERS", "")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPrecedence(t *testing.T) {
+	yamlFile := `
+server:
+  port: "9000"
+  read_timeout: 5s
+  trusted_proxies: [10.0.0.1, 10.0.0.0/8]
+redis:
+  db: 2
+smtp:
+  pool_size: 4
+`
+	tomlFile := `
+[server]
+port = "9000"
+read_timeout = "5s"
+trusted_proxies = ["10.0.0.1", "10.0.0.0/8"]
+
+[redis]
+db = 2
+
+[smtp]
+pool_size = 4
+`
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		check func(*Config) interface{}
+		want  interface{}
+	}{
+		{"default", "", nil, func(c *Config) interface{} { return c.Server.Port }, "8080"},
+		{"yaml overrides default", "config.yaml", nil, func(c *Config) interface{} { return c.Server.Port }, "9000"},
+		{"toml overrides default", "config.toml", nil, func(c *Config) interface{} { return c.Server.Port }, "9000"},
+		{"env overrides file", "config.yaml", map[string]string{"SERVER_PORT": "9100"}, func(c *Config) interface{} { return c.Server.Port }, "9100"},
+		{"empty env is unset", "config.yaml", map[string]string{"SERVER_PORT": ""}, func(c *Config) interface{} { return c.Server.Port }, "9000"},
+		{"duration from file", "config.toml", nil, func(c *Config) interface{} { return c.Server.ReadTimeout }, 5 * time.Second},
+		{"int from file", "config.yaml", nil, func(c *Config) interface{} { return c.Redis.DB }, 2},
+		{"int from env", "config.yaml", map[string]string{"SMTP_POOL_SIZE": "8"}, func(c *Config) interface{} { return c.SMTP.PoolSize }, 8},
+		{"list from file", "config.toml", nil, func(c *Config) interface{} { return c.Server.TrustedProxies }, []string{"10.0.0.1", "10.0.0.0/8"}},
+		{"list from env", "config.yaml", map[string]string{"TRUSTED_PROXIES": " 192.0.2.1 , ,192.0.2.2"}, func(c *Config) interface{} { return c.Server.TrustedProxies }, []string{"192.0.2.1", "192.0.2.2"}},
+		{"bool from env", "", map[string]string{"REDIS_REQUIRED": "true"}, func(c *Config) interface{} { return c.Redis.Required }, true},
+		{"float default", "", nil, func(c *Config) interface{} { return c.Tracing.SampleRatio }, 1.0},
+	}
+	files := map[string]string{"config.yaml": yamlFile, "config.toml": tomlFile}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tt.file != "" {
+				path = writeFile(t, tt.file, files[tt.file])
+			}
+			cfg, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.check(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOIDCProvidersFromEnvReplaceFile(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+oidc:
+  providers:
+    - name: file
+      issuer: https://file.example.com
+      client_id: file
+      redirect_url: https://app.example.com/cb
+`)
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.OIDC.Providers) != 1 || cfg.OIDC.Providers[0].Name != "file" {
+		t.Fatalf("providers from file: %+v", cfg.OIDC.Providers)
+	}
+
+	t.Setenv("OIDC_PROVIDERS", "google")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_SCOPES", "openid,email")
+	cfg, err = Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OIDCProviderConfig{{Name: "google", Issuer: "https://accounts.google.com", Scopes: []string{"openid", "email"}}}
+	if !reflect.DeepEqual(cfg.OIDC.Providers, want) {
+		t.Errorf("providers from env: %+v", cfg.OIDC.Providers)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		body   string
+		env    map[string]string
+		key    string
+		source string
+	}{
+		{"unknown key", "config.yaml", "server:\n  prot: 80\n", nil, "server.prot", "file"},
+		{"unknown section", "config.yaml", "sever: 1\n", nil, "sever", "file"},
+		{"bad duration in file", "config.toml", "[server]\nread_timeout = \"soon\"\n", nil, "server.read_timeout", "file"},
+		{"list for single value", "config.yaml", "server:\n  port: [1, 2]\n", nil, "server.port", "file"},
+		{"unsupported file type", "config.json", "{}", nil, "(file)", "file"},
+		{"bad int in env", "", "", map[string]string{"REDIS_DB": "two"}, "redis.db", "REDIS_DB"},
+		{"bad bool in env", "", "", map[string]string{"REDIS_REQUIRED": "maybe"}, "redis.required", "REDIS_REQUIRED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tt.file != "" {
+				path = writeFile(t, tt.file, tt.body)
+			}
+			_, err := Read(path)
+			var errs FieldErrors
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("got %v, want one field error", err)
+			}
+			source := tt.source
+			if source == "file" {
+				source = path
+			}
+			if errs[0].Key != tt.key || errs[0].Source != source {
+				t.Errorf("got %s (%s), want %s (%s)", errs[0].Key, errs[0].Source, tt.key, source)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redactedValue = "******"
+
+// Redacted 返回隐藏了密码、密钥等 secret 字段的副本，未设置的字段保持为空
+func (c *Config) Redacted() *Config {
+	copied := *c
+	copied.OIDC.Providers = append([]OIDCProviderConfig(nil), c.OIDC.Providers...)
+	redact(reflect.ValueOf(&copied).Elem())
+	return &copied
+}
+
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if v.Type().Field(i).Tag.Get("secret") == "true" && f.Kind() == reflect.String {
+				if f.String() != "" {
+					f.SetString(redactedValue)
+				}
+				continue
+			}
+			redact(f)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redact(v.Index(i))
+		}
+	}
+}
+
+// Print 以 YAML 格式输出配置，输出可以直接作为配置文件使用
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
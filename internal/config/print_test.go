@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setSecrets 把所有 secret 字段设为 value，返回设置的字段路径
+func setSecrets(v reflect.Value, path, value string) []string {
+	var set []string
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			p := path + "." + yamlKey(sf)
+			if sf.Tag.Get("secret") == "true" {
+				v.Field(i).SetString(value)
+				set = append(set, p)
+				continue
+			}
+			set = append(set, setSecrets(v.Field(i), p, value)...)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			set = append(set, setSecrets(v.Index(i), path, value)...)
+		}
+	}
+	return set
+}
+
+func TestRedactedHidesEverySecret(t *testing.T) {
+	const secret = "s3cret-value"
+	cfg := defaultConfig(t)
+	cfg.OIDC.Providers = []OIDCProviderConfig{{Name: "a"}, {Name: "b"}}
+	fields := setSecrets(reflect.ValueOf(cfg).Elem(), "", secret)
+
+	want := []string{".database.password", ".redis.password", ".jwt.secret", ".smtp.password", ".oidc.providers.client_secret", ".oidc.providers.client_secret", ".metrics.token"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("secret fields %v, want %v; update this test when adding a secret", fields, want)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Redacted().Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), secret) {
+		t.Errorf("redacted output contains a secret:\n%s", buf.String())
+	}
+	if n := strings.Count(buf.String(), redactedValue); n != len(fields) {
+		t.Errorf("%d redacted values, want %d", n, len(fields))
+	}
+
+	// 原配置不受影响
+	if cfg.JWT.Secret != secret || cfg.OIDC.Providers[0].ClientSecret != secret {
+		t.Error("Redacted modified the original config")
+	}
+}
+
+func TestRedactedKeepsEmptySecrets(t *testing.T) {
+	cfg := defaultConfig(t)
+	red := cfg.Redacted()
+	if red.Redis.Password != "" || red.Metrics.Token != "" {
+		t.Error("unset secrets should stay empty")
+	}
+	if red.Database.Password != redactedValue {
+		t.Error("default database password should be redacted")
+	}
+	if red.Server.Port != cfg.Server.Port {
+		t.Error("non-secret fields should be kept")
+	}
+}
+
+func TestPrintRoundTrip(t *testing.T) {
+	cfg := defaultConfig(t)
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8"}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// 输出可以直接作为配置文件使用，空列表读回后为 nil，比较再次输出的结果
+	read, err := Read(writeFile(t, "config.yaml", buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again bytes.Buffer
+	if err := read.Print(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != buf.String() {
+		t.Errorf("printed config reads back differently:\n%s\n---\n%s", buf.String(), again.String())
+	}
+}
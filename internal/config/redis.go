@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -17,6 +18,9 @@ func InitRedis(cfg *Config) (*redis.Client, error) {
 	if err := client.Ping(context.Background()).Err(); err != nil {
 		return nil, err
 	}
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return nil, err
+	}
 
 	return client, nil
 }
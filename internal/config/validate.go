@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"vapiv/pkg/logger"
+)
+
+// DefaultJWTSecret 是 JWT_SECRET 的默认值，只能用于本地开发
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
+// MinJWTSecretLength 是 release 模式下 JWT_SECRET 的最短长度
+const MinJWTSecretLength = 32
+
+// Validate 检查取值范围和配置项之间的依赖，返回 FieldErrors 列出全部问题
+func (c *Config) Validate() error {
+	var errs FieldErrors
+	add := func(key, env, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Key: key, Source: env, Msg: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(key, env, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		add(key, env, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+	port := func(key, env, value string) {
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+			add(key, env, "must be a port number between 1 and 65535, got %q", value)
+		}
+	}
+	positive := func(key, env string, d time.Duration) {
+		if d <= 0 {
+			add(key, env, "must be greater than 0")
+		}
+	}
+
+	// server
+	port("server.port", "SERVER_PORT", c.Server.Port)
+	oneOf("server.mode", "GIN_MODE", c.Server.Mode, "debug", "release", "test")
+	positive("server.read_timeout", "SERVER_READ_TIMEOUT", c.Server.ReadTimeout)
+	positive("server.read_header_timeout", "SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout)
+	positive("server.write_timeout", "SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	positive("server.idle_timeout", "SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout)
+	positive("server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+	if c.Server.MaxHeaderBytes < 4096 {
+		add("server.max_header_bytes", "SERVER_MAX_HEADER_BYTES", "must be at least 4096")
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		add("server.tls_key_file", "TLS_KEY_FILE", "tls_cert_file and tls_key_file must be set together")
+	}
+	for _, p := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(p); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(p); err != nil {
+			add("server.trusted_proxies", "TRUSTED_PROXIES", "must be an IP address or CIDR, got %q", p)
+		}
+	}
+
+	// database / redis
+	port("database.port", "DB_PORT", c.Database.Port)
+	oneOf("database.sslmode", "DB_SSLMODE", c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	port("redis.port", "REDIS_PORT", c.Redis.Port)
+	if c.Redis.DB < 0 {
+		add("redis.db", "REDIS_DB", "must not be negative")
+	}
+
+	// jwt
+	if c.Server.Mode == "release" {
+		switch {
+		case c.JWT.Secret == DefaultJWTSecret:
+			add("jwt.secret", "JWT_SECRET", "the default secret is not allowed in release mode")
+		case len(c.JWT.Secret) < MinJWTSecretLength:
+			add("jwt.secret", "JWT_SECRET", "must be at least %d characters in release mode", MinJWTSecretLength)
+		}
+		// 临时密钥只在当前进程有效，多副本之间互不认可，重启后所有登录失效
+		if c.JWT.PrivateKeyFile == "" {
+			add("jwt.private_key_file", "JWT_PRIVATE_KEY_FILE", "required in release mode")
+		}
+	}
+	if c.JWT.ExpireHour <= 0 {
+		add("jwt.expire_hour", "JWT_EXPIRE_HOUR", "must be greater than 0")
+	}
+
+	// smtp
+	oneOf("smtp.backend", "EMAIL_BACKEND", c.SMTP.Backend, "smtp", "file", "memory")
+	if c.SMTP.Backend == "smtp" {
+		if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+			add("smtp.port", "SMTP_PORT", "must be a port number between 1 and 65535, got %d", c.SMTP.Port)
+		}
+		oneOf("smtp.security", "SMTP_SECURITY", c.SMTP.Security, "starttls", "tls", "none")
+		oneOf("smtp.auth", "SMTP_AUTH", c.SMTP.Auth, "", "plain", "login", "cram-md5", "none")
+	}
+	if c.SMTP.PoolSize < 1 {
+		add("smtp.pool_size", "SMTP_POOL_SIZE", "must be at least 1")
+	}
+
+	// account
+	if c.Account.RetentionDays < 0 {
+		add("account.retention_days", "ACCOUNT_RETENTION_DAYS", "must not be negative")
+	}
+	oneOf("account.registration_mode", "REGISTRATION_MODE", c.Account.RegistrationMode, "open", "closed", "invite", "domain")
+	if c.Account.RegistrationMode == "domain" && len(c.Account.RegistrationDomains) == 0 {
+		add("account.registration_domains", "REGISTRATION_DOMAINS", "required when registration_mode is domain")
+	}
+
+	// oidc
+	seen := map[string]bool{}
+	for i, p := range c.OIDC.Providers {
+		key := fmt.Sprintf("oidc.providers[%d]", i)
+		if p.Name == "" {
+			add(key+".name", "OIDC_PROVIDERS", "must not be empty")
+		} else if seen[p.Name] {
+			add(key+".name", "OIDC_PROVIDERS", "duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+		env := "OIDC_" + strings.ToUpper(p.Name) + "_"
+		if p.Issuer == "" {
+			add(key+".issuer", env+"ISSUER", "must not be empty")
+		}
+		if p.ClientID == "" {
+			add(key+".client_id", env+"CLIENT_ID", "must not be empty")
+		}
+		if p.RedirectURL == "" {
+			add(key+".redirect_url", env+"REDIRECT_URL", "must not be empty")
+		}
+	}
+
+	// notify
+	oneOf("notify.sms_provider", "SMS_PROVIDER", c.Notify.SMSProvider, "", "mock")
+	if c.Notify.LowBalanceThreshold < 0 {
+		add("notify.low_balance_threshold", "LOW_BALANCE_THRESHOLD", "must not be negative")
+	}
+
+	// log
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		add("log.level", "LOG_LEVEL", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	oneOf("log.format", "LOG_FORMAT", strings.ToLower(c.Log.Format), "json", "text")
+	if _, err := logger.ParseGormLevel(c.Log.SQLLevel); err != nil {
+		add("log.sql_level", "DB_LOG_LEVEL", "must be silent, error, warn or info, got %q", c.Log.SQLLevel)
+	}
+	if c.Log.SlowQueryMs < 0 {
+		add("log.slow_query_ms", "DB_SLOW_QUERY_MS", "must not be negative")
+	}
+
+	// tracing
+	oneOf("tracing.exporter", "TRACING_EXPORTER", c.Tracing.Exporter, "off", "stdout", "otlp")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func defaultConfig(t *testing.T) *Config {
+	t.Helper()
+	clearEnv(t)
+	cfg, err := Read("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := defaultConfig(t).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		keys   []string
+	}{
+		{"port out of range", func(c *Config) { c.Server.Port = "70000" }, []string{"server.port"}},
+		{"unknown mode", func(c *Config) { c.Server.Mode = "prod" }, []string{"server.mode"}},
+		{"zero timeout", func(c *Config) { c.Server.WriteTimeout = 0 }, []string{"server.write_timeout"}},
+		{"small header limit", func(c *Config) { c.Server.MaxHeaderBytes = 1024 }, []string{"server.max_header_bytes"}},
+		{"tls cert without key", func(c *Config) { c.Server.TLSCertFile = "cert.pem" }, []string{"server.tls_key_file"}},
+		{"invalid trusted proxy", func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy"} }, []string{"server.trusted_proxies"}},
+		{"invalid sslmode", func(c *Config) { c.Database.SSLMode = "on" }, []string{"database.sslmode"}},
+		{"negative redis db", func(c *Config) { c.Redis.DB = -1 }, []string{"redis.db"}},
+		{"release with defaults", func(c *Config) { c.Server.Mode = "release" }, []string{"jwt.secret", "jwt.private_key_file"}},
+		{"release with short secret", func(c *Config) {
+			c.Server.Mode = "release"
+			c.JWT.Secret = "short"
+			c.JWT.PrivateKeyFile = "jwt.pem"
+		}, []string{"jwt.secret"}},
+		{"release with long secret", func(c *Config) {
+			c.Server.Mode = "release"
+			c.JWT.Secret = strings.Repeat("x", MinJWTSecretLength)
+			c.JWT.PrivateKeyFile = "jwt.pem"
+		}, nil},
+		{"zero jwt expiry", func(c *Config) { c.JWT.ExpireHour = 0 }, []string{"jwt.expire_hour"}},
+		{"unknown email backend", func(c *Config) { c.SMTP.Backend = "sendmail" }, []string{"smtp.backend"}},
+		{"smtp checks", func(c *Config) {
+			c.SMTP.Port = 0
+			c.SMTP.Security = "ssl"
+			c.SMTP.Auth = "xoauth2"
+		}, []string{"smtp.port", "smtp.security", "smtp.auth"}},
+		{"smtp checks skipped for file backend", func(c *Config) {
+			c.SMTP.Backend = "file"
+			c.SMTP.Port = 0
+		}, nil},
+		{"empty pool", func(c *Config) { c.SMTP.PoolSize = 0 }, []string{"smtp.pool_size"}},
+		{"negative retention", func(c *Config) { c.Account.RetentionDays = -1 }, []string{"account.retention_days"}},
+		{"domain mode without domains", func(c *Config) { c.Account.RegistrationMode = "domain" }, []string{"account.registration_domains"}},
+		{"incomplete oidc providers", func(c *Config) {
+			c.OIDC.Providers = []OIDCProviderConfig{
+				{Name: "a", Issuer: "https://a", ClientID: "a", RedirectURL: "https://app/cb"},
+				{Name: "a"},
+			}
+		}, []string{"oidc.providers[1].name", "oidc.providers[1].issuer", "oidc.providers[1].client_id", "oidc.providers[1].redirect_url"}},
+		{"unknown sms provider", func(c *Config) { c.Notify.SMSProvider = "twilio" }, []string{"notify.sms_provider"}},
+		{"negative threshold", func(c *Config) { c.Notify.LowBalanceThreshold = -1 }, []string{"notify.low_balance_threshold"}},
+		{"log settings", func(c *Config) {
+			c.Log.Level = "verbose"
+			c.Log.Format = "xml"
+			c.Log.SQLLevel = "all"
+			c.Log.SlowQueryMs = -1
+		}, []string{"log.level", "log.format", "log.sql_level", "log.slow_query_ms"}},
+		{"log format is case-insensitive", func(c *Config) { c.Log.Format = "TEXT" }, nil},
+		{"tracing settings", func(c *Config) {
+			c.Tracing.Exporter = "jaeger"
+			c.Tracing.SampleRatio = 1.5
+		}, []string{"tracing.exporter", "tracing.sample_ratio"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig(t)
+			tt.modify(cfg)
+			err := cfg.Validate()
+
+			var keys []string
+			var errs FieldErrors
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					keys = append(keys, e.Key)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error type %T: %v", err, err)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+				t.Errorf("errors for %v, want %v: %v", keys, tt.keys, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/endpoint"
+	"vapiv/internal/service/org"
+	"vapiv/internal/service/user"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	endpointSvc *endpoint.Service
+	userSvc     *user.Service
+	orgSvc      *org.Service
+	auditSvc    *audit.Service
+}
+
+func NewAdminHandler(endpointSvc *endpoint.Service, userSvc *user.Service, orgSvc *org.Service, auditSvc *audit.Service) *AdminHandler {
+	return &AdminHandler{endpointSvc: endpointSvc, userSvc: userSvc, orgSvc: orgSvc, auditSvc: auditSvc}
+}
+
+type CreateEndpointReq struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	Method   string `json:"method" binding:"required,oneof=GET POST PUT PATCH DELETE"`
+	Name     string `json:"name" binding:"required"`
+	Cost     int64  `json:"cost" binding:"min=0"`
+	IsPublic bool   `json:"is_public"`
+	Status   *int   `json:"status" binding:"omitempty,oneof=0 1"`
+	RateTier string `json:"rate_tier" binding:"omitempty,oneof=low standard high"`
+}
+
+type UpdateEndpointReq struct {
+	Name     *string `json:"name"`
+	Cost     *int64  `json:"cost" binding:"omitempty,min=0"`
+	IsPublic *bool   `json:"is_public"`
+	Status   *int    `json:"status" binding:"omitempty,oneof=0 1"`
+	RateTier *string `json:"rate_tier" binding:"omitempty,oneof=low standard high"`
+}
+
+type EndpointStatusReq struct {
+	Status *int `json:"status" binding:"required,oneof=0 1"`
+}
+
+type ImpersonateReq struct {
+	// 有效期（分钟），默认 15，最长 60
+	TTLMinutes int    `json:"ttl_minutes" binding:"omitempty,min=1,max=60"`
+	Write      bool   `json:"write"`
+	Reason     string `json:"reason" binding:"required,max=255"`
+}
+
+type OrgPlanReq struct {
+	Plan string `json:"plan" binding:"required,oneof=free team enterprise"`
+}
+
+type CreateInviteCodeReq struct {
+	MaxUses        int    `json:"max_uses" binding:"min=0"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"min=0"`
+	Note           string `json:"note" binding:"max=255"`
+}
+
+// ListEndpoints godoc
+// @Summary 接口配置列表
+// @Tags 管理
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/endpoints [get]
+func (h *AdminHandler) ListEndpoints(c *gin.Context) {
+	list, err := h.endpointSvc.List(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
+// StaleEndpoints godoc
+// @Summary 失效的接口配置
+// @Description 数据库中存在但路由已不存在的配置
+// @Tags 管理
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/endpoints/stale [get]
+func (h *AdminHandler) StaleEndpoints(c *gin.Context) {
+	list, err := h.endpointSvc.Stale(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
+// CreateEndpoint godoc
+// @Summary 新增接口配置
+// @Tags 管理
+// @Param body body CreateEndpointReq true "接口配置"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/endpoints [post]
+func (h *AdminHandler) CreateEndpoint(c *gin.Context) {
+	var req CreateEndpointReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	cfg := &model.APIConfig{
+		Endpoint: req.Endpoint,
+		Method:   req.Method,
+		Name:     req.Name,
+		Cost:     req.Cost,
+		IsPublic: req.IsPublic,
+		Status:   endpoint.StatusOnline,
+		RateTier: req.RateTier,
+	}
+	if cfg.RateTier == "" {
+		cfg.RateTier = endpoint.DefaultRateTier
+	}
+	if req.Status != nil {
+		cfg.Status = *req.Status
+	}
+	if err := h.endpointSvc.Create(c.Request.Context(), cfg); err != nil {
+		serviceError(c, err)
+		return
+	}
+	h.auditSvc.Record(c.Request.Context(), auditMeta(c), 0, audit.ActionAdminEndpoint, nil, cfg)
+	response.Success(c, cfg)
+}
+
+// UpdateEndpoint godoc
+// @Summary 修改接口配置
+// @Tags 管理
+// @Param id path int true "配置ID"
+// @Param body body UpdateEndpointReq true "需要修改的字段"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/endpoints/{id} [put]
+func (h *AdminHandler) UpdateEndpoint(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+
+	var req UpdateEndpointReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	fields := map[string]interface{}{}
+	if req.Name != nil {
+		fields["name"] = *req.Name
+	}
+	if req.Cost != nil {
+		fields["cost"] = *req.Cost
+	}
+	if req.IsPublic != nil {
+		fields["is_public"] = *req.IsPublic
+	}
+	if req.Status != nil {
+		fields["status"] = *req.Status
+	}
+	if req.RateTier != nil {
+		fields["rate_tier"] = *req.RateTier
+	}
+
+	before, err := h.endpointSvc.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		h.endpointError(c, err)
+		return
+	}
+	cfg, err := h.endpointSvc.Update(c.Request.Context(), uint(id), fields)
+	if err != nil {
+		h.endpointError(c, err)
+		return
+	}
+	h.auditSvc.Record(c.Request.Context(), auditMeta(c), 0, audit.ActionAdminEndpoint, before, cfg)
+	response.Success(c, cfg)
+}
+
+// SetEndpointStatus godoc
+// @Summary 上线/下线接口
+// @Description status=1 上线，status=0 下线，下线后调用方会收到 endpoint offline
+// @Tags 管理
+// @Param id path int true "配置ID"
+// @Param body body EndpointStatusReq true "状态"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/endpoints/{id}/status [put]
+func (h *AdminHandler) SetEndpointStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+
+	var req EndpointStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	before, err := h.endpointSvc.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		h.endpointError(c, err)
+		return
+	}
+	cfg, err := h.endpointSvc.SetStatus(c.Request.Context(), uint(id), *req.Status)
+	if err != nil {
+		h.endpointError(c, err)
+		return
+	}
+	h.auditSvc.Record(c.Request.Context(), auditMeta(c), 0, audit.ActionAdminEndpoint, before, cfg)
+	response.Success(c, cfg)
+}
+
+// Impersonate godoc
+// @Summary 模拟用户登录
+// @Description 签发代表该用户的短期 token，默认只读，不能修改密码、邮箱、注销账号或查看计费；期间的每个请求都会写入审计日志
+// @Tags 管理
+// @Param id path int true "用户ID"
+// @Param body body ImpersonateReq true "有效期、是否允许写操作和原因"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/users/{id}/impersonate [post]
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+
+	var req ImpersonateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	ttl := time.Duration(req.TTLMinutes) * time.Minute
+	signed, expires, err := h.userSvc.Impersonate(c.Request.Context(), c.GetUint("user_id"), uint(id), ttl, req.Write, req.Reason, auditMeta(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			response.Error(c, response.ErrNotFound, "")
+		case errors.Is(err, user.ErrCannotImpersonate):
+			response.BadRequest(c, "impersonation.not_allowed")
+		case errors.Is(err, user.ErrInvalidImpersonationTTL):
+			response.BadRequest(c, "impersonation.invalid_ttl")
+		default:
+			serviceError(c, err)
+		}
+		return
+	}
+	response.Success(c, gin.H{"token": signed, "expires_at": expires, "write": req.Write})
+}
+
+// CreateInviteCode godoc
+// @Summary 生成邀请码
+// @Description 注册模式为 invite 时使用，max_uses 为 0 表示不限次数，expires_in_hours 为 0 表示不过期
+// @Tags 管理
+// @Param body body CreateInviteCodeReq true "使用次数、有效期和备注"
+// @Success 200 {object} response.Response{data=model.InviteCode}
+// @Security BearerAuth
+// @Router /admin/invite-codes [post]
+func (h *AdminHandler) CreateInviteCode(c *gin.Context) {
+	var req CreateInviteCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInHours) * time.Hour
+	invite, err := h.userSvc.CreateInviteCode(c.Request.Context(), c.GetUint("user_id"), req.MaxUses, ttl, req.Note, auditMeta(c))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, invite)
+}
+
+// ListInviteCodes godoc
+// @Summary 邀请码列表
+// @Tags 管理
+// @Success 200 {object} response.Response{data=[]model.InviteCode}
+// @Security BearerAuth
+// @Router /admin/invite-codes [get]
+func (h *AdminHandler) ListInviteCodes(c *gin.Context) {
+	list, err := h.userSvc.ListInviteCodes(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
+// RevokeInviteCode godoc
+// @Summary 作废邀请码
+// @Tags 管理
+// @Param id path int true "邀请码ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/invite-codes/{id} [delete]
+func (h *AdminHandler) RevokeInviteCode(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+
+	if err := h.userSvc.RevokeInviteCode(c.Request.Context(), uint(id), auditMeta(c)); err != nil {
+		if errors.Is(err, user.ErrInviteNotFound) {
+			response.Error(c, response.ErrNotFound, "")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// SetOrgPlan godoc
+// @Summary 修改组织套餐
+// @Tags 管理
+// @Param id path int true "组织ID"
+// @Param body body OrgPlanReq true "套餐"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/orgs/{id}/plan [put]
+func (h *AdminHandler) SetOrgPlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+
+	var req OrgPlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	before, after, err := h.orgSvc.SetPlan(c.Request.Context(), uint(id), req.Plan)
+	if err != nil {
+		orgError(c, err)
+		return
+	}
+	h.auditSvc.Record(c.Request.Context(), auditMeta(c), 0, audit.ActionOrgPlan,
+		map[string]interface{}{"org_id": before.ID, "plan": before.Plan},
+		map[string]interface{}{"org_id": after.ID, "plan": after.Plan})
+	response.Success(c, after)
+}
+
+func (h *AdminHandler) endpointError(c *gin.Context, err error) {
+	if errors.Is(err, endpoint.ErrNotFound) {
+		response.Error(c, response.ErrNotFound, "")
+		return
+	}
+	serviceError(c, err)
+}
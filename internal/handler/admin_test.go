@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminEndpoints(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.APIConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	endpointSvc := endpoint.NewService(db, nil)
+	h := NewAdminHandler(endpointSvc, nil, nil, audit.NewService(db))
+	r := gin.New()
+	r.POST("/admin/endpoints", h.CreateEndpoint)
+	r.PUT("/admin/endpoints/:id", h.UpdateEndpoint)
+
+	if w := postJSON(r, "/admin/endpoints", gin.H{"endpoint": "/api/a", "name": "A"}); w.Code != http.StatusBadRequest {
+		t.Errorf("create without method: status %d, want 400", w.Code)
+	}
+	w := postJSON(r, "/admin/endpoints", gin.H{"endpoint": "/api/a", "method": "POST", "name": "A", "is_public": false, "status": 0})
+	if w.Code != http.StatusOK {
+		t.Fatalf("create: status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data model.APIConfig `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	cfg := resp.Data
+	if cfg.Method != "POST" || cfg.IsPublic || cfg.Status != endpoint.StatusOffline || cfg.RateTier != endpoint.DefaultRateTier {
+		t.Errorf("created config %+v", cfg)
+	}
+
+	update := func(body gin.H) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("PUT", "/admin/endpoints/"+strconv.FormatUint(uint64(cfg.ID), 10), bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := update(gin.H{"rate_tier": "unlimited"}); w.Code != http.StatusBadRequest {
+		t.Errorf("update to unknown rate tier: status %d, want 400", w.Code)
+	}
+	if w := update(gin.H{"rate_tier": endpoint.RateTierHigh}); w.Code != http.StatusOK {
+		t.Errorf("update rate tier: status %d: %s", w.Code, w.Body)
+	}
+	if got, _ := endpointSvc.Get("/api/a"); got.RateTier != endpoint.RateTierHigh {
+		t.Errorf("rate tier %q, want %q", got.RateTier, endpoint.RateTierHigh)
+	}
+}
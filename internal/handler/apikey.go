@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"vapiv/internal/service/user"
 	"vapiv/pkg/response"
@@ -20,20 +22,39 @@ func NewAPIKeyHandler(svc *user.Service) *APIKeyHandler {
 func (h *APIKeyHandler) Create(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	name := c.DefaultQuery("name", "default")
+	expiresAt, ok := keyExpiry(c)
+	if !ok {
+		return
+	}
 
-	key, err := h.svc.CreateAPIKey(userID, name)
+	key, err := h.svc.CreateAPIKey(c.Request.Context(), userID, nil, name, expiresAt, auditMeta(c))
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, key)
 }
 
+// keyExpiry 解析 expires_in_days 参数（1 到 3650 天），为空时永不过期，失败时已写入响应
+func keyExpiry(c *gin.Context) (*time.Time, bool) {
+	v := c.Query("expires_in_days")
+	if v == "" {
+		return nil, true
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 1 || days > 3650 {
+		response.BadRequest(c, "apikey.invalid_expiry")
+		return nil, false
+	}
+	t := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	return &t, true
+}
+
 func (h *APIKeyHandler) List(c *gin.Context) {
 	userID := c.GetUint("user_id")
-	keys, err := h.svc.ListAPIKeys(userID)
+	keys, err := h.svc.ListAPIKeys(c.Request.Context(), userID)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, keys)
@@ -43,9 +64,32 @@ func (h *APIKeyHandler) Delete(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	keyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 
-	if err := h.svc.DeleteAPIKey(userID, uint(keyID)); err != nil {
-		response.Error(c, 500, err.Error())
+	if err := h.svc.DeleteAPIKey(c.Request.Context(), userID, uint(keyID), auditMeta(c)); err != nil {
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, nil)
 }
+
+// Rotate godoc
+// @Summary 轮换 API Key
+// @Description 生成新的 Key 值，旧值立即失效
+// @Tags 用户
+// @Param id path int true "Key ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/apikey/{id}/rotate [post]
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	keyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	key, err := h.svc.RotateAPIKey(c.Request.Context(), c.GetUint("user_id"), uint(keyID), auditMeta(c))
+	if err != nil {
+		if errors.Is(err, user.ErrKeyNotFound) {
+			response.Error(c, response.ErrNotFound, "")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, key)
+}
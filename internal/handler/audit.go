@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"vapiv/internal/service/audit"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	svc *audit.Service
+}
+
+func NewAuditHandler(svc *audit.Service) *AuditHandler {
+	return &AuditHandler{svc: svc}
+}
+
+// ListMine godoc
+// @Summary 我的安全日志
+// @Description 登录、API Key、密码、邮箱等敏感操作记录
+// @Tags 用户
+// @Param page query int false "页码"
+// @Param size query int false "每页条数，最多 100"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/audit [get]
+func (h *AuditHandler) ListMine(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	size, _ := strconv.Atoi(c.Query("size"))
+
+	events, total, err := h.svc.ListForUser(c.Request.Context(), c.GetUint("user_id"), page, size)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"list": events, "total": total})
+}
+
+// Search godoc
+// @Summary 查询审计日志
+// @Tags 管理
+// @Param user_id query int false "被操作的用户ID"
+// @Param actor_id query int false "操作人ID"
+// @Param action query string false "事件类型，如 login.failure"
+// @Param from query string false "开始时间 RFC3339"
+// @Param to query string false "结束时间 RFC3339"
+// @Param page query int false "页码"
+// @Param size query int false "每页条数，最多 100"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func (h *AuditHandler) Search(c *gin.Context) {
+	var f audit.Filter
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	actorID, _ := strconv.ParseUint(c.Query("actor_id"), 10, 32)
+	f.UserID = uint(userID)
+	f.ActorID = uint(actorID)
+	f.Action = c.Query("action")
+	f.Page, _ = strconv.Atoi(c.Query("page"))
+	f.Size, _ = strconv.Atoi(c.Query("size"))
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if f.From, err = time.Parse(time.RFC3339, v); err != nil {
+			response.BadRequest(c, "request.invalid_from")
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if f.To, err = time.Parse(time.RFC3339, v); err != nil {
+			response.BadRequest(c, "request.invalid_to")
+			return
+		}
+	}
+
+	events, total, err := h.svc.Search(c.Request.Context(), f)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"list": events, "total": total})
+}
+
+// auditMeta 从请求中取出操作人、IP 和 User-Agent，模拟登录时操作人是管理员
+func auditMeta(c *gin.Context) audit.Meta {
+	actor := c.GetUint("user_id")
+	if id := c.GetUint("impersonator_id"); id != 0 {
+		actor = id
+	}
+	return audit.Meta{
+		ActorID:   actor,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
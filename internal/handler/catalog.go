@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"vapiv/internal/service/endpoint"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CatalogHandler struct {
+	catalog *endpoint.Catalog
+}
+
+func NewCatalogHandler(catalog *endpoint.Catalog) *CatalogHandler {
+	return &CatalogHandler{catalog: catalog}
+}
+
+// List godoc
+// @Summary 接口目录
+// @Description 所有已上线接口的名称、价格、认证方式、限流等级和参数说明，支持 ETag 缓存
+// @Tags 公共
+// @Param If-None-Match header string false "上次返回的 ETag"
+// @Success 200 {object} response.Response
+// @Success 304
+// @Router /catalog [get]
+func (h *CatalogHandler) List(c *gin.Context) {
+	entries := h.catalog.Entries()
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=60")
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	response.Success(c, entries)
+}
+
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors godoc
+// @Summary 错误码目录
+// @Description 失败响应的 error 字段取值、对应的 HTTP 状态码和说明（按请求语言）。旧客户端可以在请求头带上 X-Legacy-Status: 1，错误响应仍返回 HTTP 200（400 和 401 除外）
+// @Tags 公共
+// @Param lang query string false "zh-CN 或 en，默认按 Accept-Language"
+// @Success 200 {object} response.Response{data=[]response.ErrorCode}
+// @Router /catalog/errors [get]
+func (h *CatalogHandler) Errors(c *gin.Context) {
+	locale := response.Locale(c)
+	list := response.Catalog()
+	for i := range list {
+		list[i].Message = response.T(locale, list[i].Key)
+	}
+	response.Success(c, list)
+}
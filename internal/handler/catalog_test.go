@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCatalogETag(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.APIConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := endpoint.NewService(db, nil)
+	cfg := &model.APIConfig{Endpoint: "/api/a", Method: "GET", Name: "A", Cost: 1, IsPublic: true, Status: endpoint.StatusOnline}
+	if err := svc.Create(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := endpoint.NewCatalog(svc, `{"paths": {}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/catalog", NewCatalogHandler(catalog).List)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/catalog", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, etag %q", w.Code, etag)
+	}
+	if again := get("").Header().Get("ETag"); again != etag {
+		t.Errorf("etag changed without a config change: %s -> %s", etag, again)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := get(tt.ifNoneMatch)
+		if w.Code != tt.status {
+			t.Errorf("If-None-Match %s: status %d, want %d", tt.ifNoneMatch, w.Code, tt.status)
+		}
+		if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: 304 with a body", tt.ifNoneMatch)
+		}
+	}
+
+	// 修改价格后旧的 ETag 失效
+	if _, err := svc.Update(ctx, cfg.ID, map[string]interface{}{"cost": 2}); err != nil {
+		t.Fatal(err)
+	}
+	w = get(etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after a price change: status %d, etag %s", w.Code, w.Header().Get("ETag"))
+	}
+}
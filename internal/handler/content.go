@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"vapiv/internal/service/content"
+	"vapiv/internal/service/settings"
 	"vapiv/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -14,9 +15,9 @@ type ContentHandler struct {
 	qqSvc   *content.QQService
 }
 
-func NewContentHandler() *ContentHandler {
+func NewContentHandler(settingsSvc *settings.Service) *ContentHandler {
 	return &ContentHandler{
-		biliSvc: content.NewBilibiliService(),
+		biliSvc: content.NewBilibiliService(settingsSvc),
 		qqSvc:   content.NewQQService(),
 	}
 }
@@ -31,13 +32,13 @@ func NewContentHandler() *ContentHandler {
 func (h *ContentHandler) BilibiliVideo(c *gin.Context) {
 	bvid := c.Query("bvid")
 	if bvid == "" {
-		response.BadRequest(c, "bvid is required")
+		response.BadRequest(c, "content.bvid_required")
 		return
 	}
 
-	info, err := h.biliSvc.GetVideoInfo(bvid)
+	info, err := h.biliSvc.GetVideoInfo(c.Request.Context(), bvid)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, info)
@@ -53,7 +54,7 @@ func (h *ContentHandler) BilibiliVideo(c *gin.Context) {
 func (h *ContentHandler) QQAvatar(c *gin.Context) {
 	qq := c.Query("qq")
 	if qq == "" {
-		response.BadRequest(c, "qq is required")
+		response.BadRequest(c, "content.qq_required")
 		return
 	}
 
@@ -71,13 +72,13 @@ func (h *ContentHandler) QQAvatar(c *gin.Context) {
 func (h *ContentHandler) BilibiliVideoURL(c *gin.Context) {
 	bvid := c.Query("bvid")
 	if bvid == "" {
-		response.BadRequest(c, "bvid is required")
+		response.BadRequest(c, "content.bvid_required")
 		return
 	}
 
-	url, err := h.biliSvc.GetVideoURL(bvid)
+	url, err := h.biliSvc.GetVideoURL(c.Request.Context(), bvid)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, url)
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"vapiv/internal/service/core"
+	"vapiv/internal/service/settings"
 	"vapiv/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -13,11 +14,11 @@ type CoreHandler struct {
 	douyinSvc *core.DouyinService
 }
 
-func NewCoreHandler() *CoreHandler {
+func NewCoreHandler(settingsSvc *settings.Service) *CoreHandler {
 	return &CoreHandler{
-		ipSvc:     core.NewIPService(),
+		ipSvc:     core.NewIPService(settingsSvc),
 		cryptoSvc: core.NewCryptoService(),
-		douyinSvc: core.NewDouyinService(),
+		douyinSvc: core.NewDouyinService(settingsSvc),
 	}
 }
 
@@ -29,9 +30,9 @@ func NewCoreHandler() *CoreHandler {
 // @Router /api/ip [get]
 func (h *CoreHandler) IPQuery(c *gin.Context) {
 	ip := c.DefaultQuery("ip", c.ClientIP())
-	info, err := h.ipSvc.Query(ip)
+	info, err := h.ipSvc.Query(c.Request.Context(), ip)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, info)
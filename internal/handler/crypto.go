@@ -28,7 +28,7 @@ func (h *CoreHandler) AESEncrypt(c *gin.Context) {
 
 	result, err := h.cryptoSvc.AESEncrypt(req.Text, req.Key)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, gin.H{"encrypted": result})
@@ -51,7 +51,7 @@ func (h *CoreHandler) AESDecrypt(c *gin.Context) {
 
 	result, err := h.cryptoSvc.AESDecrypt(req.Text, req.Key)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, gin.H{"decrypted": result})
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"vapiv/pkg/email"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DevMailHandler 只在 EMAIL_BACKEND=memory 时注册，用于开发和集成测试读取发出的邮件
+type DevMailHandler struct {
+	inbox *email.MemorySender
+}
+
+func NewDevMailHandler(inbox *email.MemorySender) *DevMailHandler {
+	return &DevMailHandler{inbox: inbox}
+}
+
+// List godoc
+// @Summary 内存收件箱
+// @Description 仅 EMAIL_BACKEND=memory 时可用，按时间倒序
+// @Tags 开发
+// @Param to query string false "收件人"
+// @Success 200 {object} response.Response
+// @Router /dev/mail [get]
+func (h *DevMailHandler) List(c *gin.Context) {
+	response.Success(c, h.inbox.Messages(c.Query("to")))
+}
+
+// Get godoc
+// @Summary 内存收件箱中的邮件
+// @Tags 开发
+// @Param id path string true "邮件ID"
+// @Success 200 {object} response.Response
+// @Router /dev/mail/{id} [get]
+func (h *DevMailHandler) Get(c *gin.Context) {
+	m, ok := h.inbox.Get(c.Param("id"))
+	if !ok {
+		response.Error(c, response.ErrNotFound, "devmail.not_found")
+		return
+	}
+	response.Success(c, m)
+}
+
+// Clear godoc
+// @Summary 清空内存收件箱
+// @Tags 开发
+// @Success 200 {object} response.Response
+// @Router /dev/mail [delete]
+func (h *DevMailHandler) Clear(c *gin.Context) {
+	h.inbox.Clear()
+	response.Success(c, nil)
+}
@@ -18,13 +18,13 @@ import (
 func (h *CoreHandler) DouyinVideo(c *gin.Context) {
 	url := c.Query("url")
 	if url == "" {
-		response.BadRequest(c, "url参数不能为空")
+		response.BadRequest(c, "content.url_required")
 		return
 	}
 
-	video, err := h.douyinSvc.ParseVideo(url)
+	video, err := h.douyinSvc.ParseVideo(c.Request.Context(), url)
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		serviceError(c, err)
 		return
 	}
 
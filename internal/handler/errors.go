@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"vapiv/internal/service/core"
+	"vapiv/internal/service/endpoint"
+	"vapiv/internal/service/upstream"
+	"vapiv/internal/service/user"
+	"vapiv/pkg/captcha"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// serviceError 把 service 返回的错误转换为错误目录中的错误码。
+// 原始错误可能包含上游响应、SQL 等细节，只和 request_id 一起写入日志，不返回给调用方
+func serviceError(c *gin.Context, err error) {
+	e := response.ErrInternal
+	switch {
+	case errors.Is(err, upstream.ErrInvalidInput), errors.Is(err, core.ErrInvalidCiphertext):
+		e = response.ErrBadRequest
+	case errors.Is(err, upstream.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, endpoint.ErrNotFound), errors.Is(err, user.ErrUserNotFound):
+		e = response.ErrNotFound
+	case errors.Is(err, upstream.ErrBlocked):
+		e = response.ErrUpstreamBlocked
+	case errors.Is(err, upstream.ErrParse):
+		e = response.ErrUpstreamParse
+	case errors.Is(err, upstream.ErrUnavailable):
+		e = response.ErrUpstreamUnavailable
+	case errors.Is(err, captcha.ErrRedisUnavailable):
+		e = response.ErrUnavailable
+	}
+
+	logError(c, "service error", e, err)
+	response.Error(c, e, "")
+}
+
+// logError 记录不返回给调用方的原始错误，调用方根据 request_id 排查
+func logError(c *gin.Context, msg string, e response.ErrorCode, err error) {
+	level := slog.LevelError
+	if e.Status < 500 {
+		level = slog.LevelWarn
+	}
+	slog.Log(c.Request.Context(), level, msg,
+		slog.String("request_id", c.GetString("request_id")),
+		slog.String("code", e.Code),
+		slog.String("error", err.Error()),
+	)
+}
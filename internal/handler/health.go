@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"vapiv/internal/service/health"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	svc *health.Service
+}
+
+func NewHealthHandler(svc *health.Service) *HealthHandler {
+	return &HealthHandler{svc: svc}
+}
+
+// Livez godoc
+// @Summary 存活检查
+// @Description 进程能处理请求即返回 200，不检查数据库和 Redis，用于 liveness probe
+// @Tags 公共
+// @Success 200 {object} map[string]string
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readyz godoc
+// @Summary 就绪检查
+// @Description 检查数据库和 Redis。必需依赖不可用时返回 503；只有可选的 Redis 不可用时返回 200，status 为 degraded。错误详情见 /admin/health
+// @Tags 公共
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.svc.Ready(c.Request.Context()).Redacted()
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Detail godoc
+// @Summary 服务状态详情
+// @Description 依赖检查结果（包括错误详情），以及根据最近 10 分钟调用成功率估计的第三方服务可达性
+// @Tags 管理
+// @Success 200 {object} response.Response{data=health.Detail}
+// @Security BearerAuth
+// @Router /admin/health [get]
+func (h *HealthHandler) Detail(c *gin.Context) {
+	response.Success(c, h.svc.Detail(c.Request.Context()))
+}
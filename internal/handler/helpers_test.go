@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/notification"
+	"vapiv/internal/service/user"
+	"vapiv/pkg/captcha"
+	"vapiv/pkg/token"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestDB 返回一个独立的内存 SQLite 数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	// 每个连接是一个新的内存数据库，只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&model.User{}, &model.UserIdentity{}, &model.AuditEvent{}, &model.InviteCode{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestUserService(t *testing.T, db *gorm.DB, reg user.Registration) *user.Service {
+	t.Helper()
+	keys, err := token.NewKeySet(nil, nil, "vapiv", "vapiv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	return user.NewService(db, keys, 1, notification.NewService(db), captcha.NewService(rdb), audit.NewService(db), reg)
+}
+
+// solveChallenge 领取并解出一道工作量证明题，返回提交用的 token
+func solveChallenge(t *testing.T, svc *user.Service) string {
+	t.Helper()
+	ch, err := svc.NewChallenge(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch.ID + "." + captcha.Solve(ch.Prefix, ch.Difficulty)
+}
+
+// withUser 模拟 JWT 中间件，按 X-Test-User 设置当前用户
+func withUser(c *gin.Context) {
+	if id, err := strconv.ParseUint(c.GetHeader("X-Test-User"), 10, 32); err == nil {
+		c.Set("user_id", uint(id))
+	}
+}
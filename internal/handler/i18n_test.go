@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"vapiv/pkg/response"
+)
+
+// handler 和中间件中传给 response 的消息 key 都必须有翻译
+var messageKeyPattern = regexp.MustCompile(`response\.(?:Error|BadRequest|Unauthorized)\(c, (?:response\.\w+, )?"([a-z_]+\.[a-z_]+)"`)
+
+func TestUsedKeysExist(t *testing.T) {
+	err := filepath.Walk("..", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range messageKeyPattern.FindAllStringSubmatch(string(src), -1) {
+			// 缺少翻译时 T 原样返回 key
+			if response.T(response.DefaultLocale, m[1]) == m[1] {
+				t.Errorf("%s: message key %q is not in locales", path, m[1])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
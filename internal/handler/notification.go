@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"vapiv/internal/service/notification"
+	"vapiv/pkg/notify"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	svc *notification.Service
+}
+
+func NewNotificationHandler(svc *notification.Service) *NotificationHandler {
+	return &NotificationHandler{svc: svc}
+}
+
+type NotificationSettingReq struct {
+	Event   string `json:"event" binding:"required,oneof=verification low_balance key_expiry security"`
+	Channel string `json:"channel" binding:"required"`
+	// 邮箱、手机号或 webhook 地址；email 渠道为空时发送到账号邮箱
+	Target string `json:"target" binding:"max=500"`
+	// webhook 签名密钥或机器人加签密钥，为空时保留原值
+	Secret  string `json:"secret" binding:"max=255"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// List godoc
+// @Summary 通知设置
+// @Description 返回可选的事件、已启用的渠道和当前设置；没有设置的事件默认发送到账号邮箱
+// @Tags 用户
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/notifications [get]
+func (h *NotificationHandler) List(c *gin.Context) {
+	settings, err := h.svc.Settings(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, gin.H{
+		"events":   notify.Events,
+		"channels": h.svc.Channels(),
+		"settings": settings,
+	})
+}
+
+// Set godoc
+// @Summary 新增或修改通知设置
+// @Tags 用户
+// @Param body body NotificationSettingReq true "事件和渠道"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/notifications [put]
+func (h *NotificationHandler) Set(c *gin.Context) {
+	var req NotificationSettingReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	setting, err := h.svc.SetPreference(c.Request.Context(), c.GetUint("user_id"), req.Event, req.Channel, req.Target, req.Secret, enabled)
+	if err != nil {
+		notificationError(c, err)
+		return
+	}
+	response.Success(c, setting)
+}
+
+// Delete godoc
+// @Summary 删除通知设置
+// @Tags 用户
+// @Param id path int true "设置ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/notifications/{id} [delete]
+func (h *NotificationHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+	if err := h.svc.DeletePreference(c.Request.Context(), c.GetUint("user_id"), uint(id)); err != nil {
+		notificationError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Test godoc
+// @Summary 发送测试通知
+// @Tags 用户
+// @Param id path int true "设置ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/notifications/{id}/test [post]
+func (h *NotificationHandler) Test(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+	if err := h.svc.Test(c.Request.Context(), c.GetUint("user_id"), uint(id)); err != nil {
+		notificationError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+func notificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, notification.ErrNotFound):
+		response.Error(c, response.ErrNotFound, "")
+	case errors.Is(err, notify.ErrForbiddenAddress):
+		response.BadRequest(c, "notification.forbidden_address")
+	case errors.Is(err, notification.ErrUnknownEvent),
+		errors.Is(err, notification.ErrUnknownChannel),
+		errors.Is(err, notify.ErrInvalidTarget):
+		response.BadRequest(c, "notification.invalid_target")
+	default:
+		// 发送失败的原因（状态码、连接错误）只写日志，避免把测试通知当作探测内网端口的手段
+		logError(c, "notification send failed", response.ErrUpstream, err)
+		response.Error(c, response.ErrUpstream, "notification.send_failed")
+	}
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vapiv/internal/service/user"
+	"vapiv/pkg/oidc"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcCookie     = "vapiv_oidc"
+	oidcCookiePath = "/auth/oidc"
+	oidcStateTTL   = 10 * time.Minute
+)
+
+type OIDCHandler struct {
+	svc        *user.Service
+	providers  map[string]*oidc.Provider
+	secret     []byte
+	successURL string
+}
+
+func NewOIDCHandler(svc *user.Service, providers []*oidc.Provider, secret, successURL string) *OIDCHandler {
+	m := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		m[p.Name()] = p
+	}
+	return &OIDCHandler{svc: svc, providers: m, secret: []byte(secret), successURL: successURL}
+}
+
+// oidcState 保存在签名 cookie 中，回调时校验 state 并取回 nonce 和 PKCE verifier
+type oidcState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	LinkUser uint   `json:"l,omitempty"`
+	Expires  int64  `json:"e"`
+}
+
+// Providers godoc
+// @Summary 可用的外部登录方式
+// @Tags 认证
+// @Success 200 {object} response.Response
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	response.Success(c, names)
+}
+
+// Login godoc
+// @Summary 外部身份登录
+// @Description 跳转到身份提供方授权（authorization code + PKCE）
+// @Tags 认证
+// @Param provider path string true "提供方名称"
+// @Success 302
+// @Router /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, ok := h.start(c, 0)
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Link godoc
+// @Summary 关联外部身份
+// @Description 返回授权地址，浏览器访问后在回调中关联到当前账号
+// @Tags 用户
+// @Param provider path string true "提供方名称"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/identities/{provider}/link [post]
+func (h *OIDCHandler) Link(c *gin.Context) {
+	authURL, ok := h.start(c, c.GetUint("user_id"))
+	if !ok {
+		return
+	}
+	response.Success(c, gin.H{"auth_url": authURL})
+}
+
+// Callback godoc
+// @Summary 外部身份登录回调
+// @Tags 认证
+// @Param provider path string true "提供方名称"
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 200 {object} response.Response
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		response.Error(c, response.ErrNotFound, "oidc.unknown_provider")
+		return
+	}
+
+	st, err := h.readState(c)
+	c.SetCookie(oidcCookie, "", -1, oidcCookiePath, "", c.Request.TLS != nil, true)
+	if err != nil || st.Provider != provider.Name() || st.State != c.Query("state") {
+		response.BadRequest(c, "oidc.invalid_state")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		response.BadRequest(c, "oidc.authorization_failed", e)
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), c.Query("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		// 错误中包含提供方的响应内容，只写日志
+		logError(c, "oidc exchange failed", response.ErrUnauthorized, err)
+		response.Unauthorized(c, "oidc.exchange_failed")
+		return
+	}
+
+	token, err := h.svc.LoginWithIdentity(c.Request.Context(), provider.Name(), claims, st.LinkUser, auditMeta(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrIdentityLinked):
+			response.BadRequest(c, "oidc.identity_linked")
+		case errors.Is(err, user.ErrEmailRequired):
+			response.BadRequest(c, "oidc.email_required")
+		case errors.Is(err, user.ErrEmailUnverified):
+			response.BadRequest(c, "oidc.email_unverified")
+		case errors.Is(err, user.ErrUserDisabled):
+			response.Unauthorized(c, "oidc.user_disabled")
+		case errors.Is(err, user.ErrInviteRequired):
+			// 外部身份不能使用邀请码注册
+			response.Error(c, response.ErrRegistrationClosed, "user.invite_required")
+		default:
+			if !registrationError(c, err) {
+				serviceError(c, err)
+			}
+		}
+		return
+	}
+
+	if h.successURL != "" {
+		c.Redirect(http.StatusFound, h.successURL+"#token="+url.QueryEscape(token))
+		return
+	}
+	response.Success(c, gin.H{"token": token})
+}
+
+// Identities godoc
+// @Summary 已关联的外部身份
+// @Tags 用户
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/identities [get]
+func (h *OIDCHandler) Identities(c *gin.Context) {
+	list, err := h.svc.ListIdentities(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, list)
+}
+
+// Unlink godoc
+// @Summary 取消关联外部身份
+// @Tags 用户
+// @Param provider path string true "提供方名称"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/identities/{provider} [delete]
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	if err := h.svc.UnlinkIdentity(c.Request.Context(), c.GetUint("user_id"), c.Param("provider"), auditMeta(c)); err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// start 生成 state/nonce/verifier 写入 cookie 并返回授权地址，失败时已写入响应
+func (h *OIDCHandler) start(c *gin.Context, linkUser uint) (string, bool) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		response.Error(c, response.ErrNotFound, "oidc.unknown_provider")
+		return "", false
+	}
+
+	st := oidcState{
+		Provider: provider.Name(),
+		State:    oidc.RandomString(),
+		Nonce:    oidc.RandomString(),
+		Verifier: oidc.RandomString(),
+		LinkUser: linkUser,
+		Expires:  time.Now().Add(oidcStateTTL).Unix(),
+	}
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		logError(c, "oidc discovery failed", response.ErrUpstreamUnavailable, err)
+		response.Error(c, response.ErrUpstreamUnavailable, "")
+		return "", false
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookie, h.signState(st), int(oidcStateTTL.Seconds()), oidcCookiePath, "", c.Request.TLS != nil, true)
+	return authURL, true
+}
+
+func (h *OIDCHandler) signState(st oidcState) string {
+	data, _ := json.Marshal(st)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + h.mac(payload)
+}
+
+func (h *OIDCHandler) readState(c *gin.Context) (*oidcState, error) {
+	value, err := c.Cookie(oidcCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(h.mac(payload))) {
+		return nil, errors.New("bad signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var st oidcState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > st.Expires {
+		return nil, errors.New("state expired")
+	}
+	return &st, nil
+}
+
+func (h *OIDCHandler) mac(payload string) string {
+	m := hmac.New(sha256.New, h.secret)
+	m.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
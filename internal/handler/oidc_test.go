@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/user"
+	"vapiv/pkg/oidc"
+	"vapiv/pkg/oidc/mock"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type oidcEnv struct {
+	db  *gorm.DB
+	idp *mock.Server
+	r   *gin.Engine
+}
+
+func newOIDCEnv(t *testing.T, reg user.Registration) *oidcEnv {
+	t.Helper()
+	idp, err := mock.New("", "vapiv", "secret", mock.User{Subject: "sub-1", Email: "dev@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(idp)
+	t.Cleanup(srv.Close)
+	idp.Issuer = srv.URL
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       srv.URL,
+		ClientID:     "vapiv",
+		ClientSecret: "secret",
+		RedirectURL:  "http://vapiv.test/auth/oidc/mock/callback",
+	}, srv.Client())
+
+	db := newTestDB(t)
+	h := NewOIDCHandler(newTestUserService(t, db, reg), []*oidc.Provider{provider}, "state-secret", "")
+
+	r := gin.New()
+	r.GET("/auth/oidc/:provider/login", h.Login)
+	r.GET("/auth/oidc/:provider/callback", h.Callback)
+	r.POST("/user/identities/:provider/link", withUser, h.Link)
+	r.DELETE("/user/identities/:provider", withUser, h.Unlink)
+	return &oidcEnv{db: db, idp: idp, r: r}
+}
+
+func (e *oidcEnv) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	e.r.ServeHTTP(w, req)
+	return w
+}
+
+// start 发起登录（userID 为 0）或关联，返回授权地址和 state cookie
+func (e *oidcEnv) start(t *testing.T, userID uint) (string, *http.Cookie) {
+	t.Helper()
+	var w *httptest.ResponseRecorder
+	var authURL string
+	if userID == 0 {
+		w = e.do(httptest.NewRequest("GET", "/auth/oidc/mock/login", nil))
+		if w.Code != http.StatusFound {
+			t.Fatalf("login status = %d: %s", w.Code, w.Body)
+		}
+		authURL = w.Header().Get("Location")
+	} else {
+		req := httptest.NewRequest("POST", "/user/identities/mock/link", nil)
+		req.Header.Set("X-Test-User", strconv.Itoa(int(userID)))
+		w = e.do(req)
+		var body struct {
+			Data struct {
+				AuthURL string `json:"auth_url"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		authURL = body.Data.AuthURL
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcCookie {
+			return authURL, c
+		}
+	}
+	t.Fatal("state cookie not set")
+	return "", nil
+}
+
+// authorize 在 mock 提供方完成授权，返回回调的查询参数
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query()
+}
+
+func (e *oidcEnv) callback(query url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/auth/oidc/mock/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	return e.do(req)
+}
+
+// login 走完整的登录或关联流程
+func (e *oidcEnv) login(t *testing.T, userID uint) *httptest.ResponseRecorder {
+	t.Helper()
+	authURL, cookie := e.start(t, userID)
+	return e.callback(authorize(t, authURL), cookie)
+}
+
+func errorCode(w *httptest.ResponseRecorder) string {
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body.Error + ": " + body.Message
+}
+
+func (e *oidcEnv) count(t *testing.T, m interface{}, where string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := e.db.Model(m).Where(where, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOIDCCallbackRejectsBadState(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{})
+	authURL, cookie := env.start(t, 0)
+	query := authorize(t, authURL)
+
+	tampered := url.Values{"code": {query.Get("code")}, "state": {"other"}}
+	if w := env.callback(tampered, cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("wrong state: status = %d, want 400", w.Code)
+	}
+	if w := env.callback(query, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("missing cookie: status = %d, want 400", w.Code)
+	}
+	forged := *cookie
+	forged.Value = strings.Replace(cookie.Value, ".", "x.", 1)
+	if w := env.callback(query, &forged); w.Code != http.StatusBadRequest {
+		t.Errorf("forged cookie: status = %d, want 400", w.Code)
+	}
+	if n := env.count(t, &model.User{}, "1 = 1"); n != 0 {
+		t.Errorf("%d users created", n)
+	}
+}
+
+func TestOIDCCallbackHidesExchangeError(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{})
+	authURL, cookie := env.start(t, 0)
+	query := authorize(t, authURL)
+	query.Set("code", "unknown-code")
+
+	w := env.callback(query, cookie)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "invalid_grant") || strings.Contains(w.Body.String(), "status 400") {
+		t.Errorf("response leaks the token endpoint error: %s", w.Body)
+	}
+}
+
+func TestOIDCLoginCreatesUserForVerifiedEmail(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{})
+	w := env.login(t, 0)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"token"`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.User{}, "email = ?", "dev@example.com"); n != 1 {
+		t.Fatalf("users with email = %d, want 1", n)
+	}
+	if n := env.count(t, &model.UserIdentity{}, "provider = ? AND subject = ?", "mock", "sub-1"); n != 1 {
+		t.Errorf("identities = %d, want 1", n)
+	}
+
+	// 再次登录使用已关联的身份，不会重复创建
+	if w := env.login(t, 0); w.Code != http.StatusOK {
+		t.Fatalf("second login status = %d: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.User{}, "1 = 1"); n != 1 {
+		t.Errorf("users = %d, want 1", n)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{})
+	env.idp.User.EmailVerified = false
+
+	w := env.login(t, 0)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.User{}, "1 = 1"); n != 0 {
+		t.Errorf("users = %d, want 0", n)
+	}
+}
+
+func TestOIDCLoginDoesNotClaimExistingAccountWithUnverifiedEmail(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{})
+	victim := model.User{Username: "victim", Email: "dev@example.com", Password: "x"}
+	env.db.Create(&victim)
+	env.idp.User.EmailVerified = false
+
+	if w := env.login(t, 0); w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.UserIdentity{}, "user_id = ?", victim.ID); n != 0 {
+		t.Errorf("identity linked to the existing account")
+	}
+
+	// 邮箱验证后按邮箱关联到已有账号
+	env.idp.User.EmailVerified = true
+	if w := env.login(t, 0); w.Code != http.StatusOK {
+		t.Fatalf("verified status = %d: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.UserIdentity{}, "user_id = ?", victim.ID); n != 1 {
+		t.Errorf("identities for existing account = %d, want 1", n)
+	}
+}
+
+func TestOIDCDomainRegistrationRequiresVerifiedEmail(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{Mode: user.RegistrationDomain, AllowedDomains: []string{"corp.com"}})
+	env.idp.User.Email = "mallory@corp.com"
+	env.idp.User.EmailVerified = false
+
+	if w := env.login(t, 0); w.Code != http.StatusBadRequest {
+		t.Fatalf("unverified status = %d, want 400: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.User{}, "1 = 1"); n != 0 {
+		t.Fatalf("users = %d, want 0", n)
+	}
+
+	env.idp.User.EmailVerified = true
+	if w := env.login(t, 0); w.Code != http.StatusOK {
+		t.Fatalf("verified status = %d: %s", w.Code, w.Body)
+	}
+	env.idp.User.Subject = "sub-2"
+	env.idp.User.Email = "someone@other.com"
+	if w := env.login(t, 0); w.Code != http.StatusForbidden {
+		t.Errorf("other domain status = %d, want 403: %s", w.Code, errorCode(w))
+	}
+}
+
+func TestOIDCLinkAndUnlink(t *testing.T) {
+	env := newOIDCEnv(t, user.Registration{})
+	alice := model.User{Username: "alice", Email: "alice@example.com", Password: "x", Status: 1}
+	bob := model.User{Username: "bob", Email: "bob@example.com", Password: "x", Status: 1}
+	env.db.Create(&alice)
+	env.db.Create(&bob)
+
+	// 关联不要求外部邮箱与账号一致
+	env.idp.User.EmailVerified = false
+	if w := env.login(t, alice.ID); w.Code != http.StatusOK {
+		t.Fatalf("link status = %d: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.UserIdentity{}, "user_id = ? AND subject = ?", alice.ID, "sub-1"); n != 1 {
+		t.Fatalf("identity not linked to alice")
+	}
+
+	// 已关联到 alice 的身份不能再关联给 bob
+	if w := env.login(t, bob.ID); w.Code != http.StatusBadRequest {
+		t.Errorf("link to bob status = %d, want 400", w.Code)
+	}
+
+	req := httptest.NewRequest("DELETE", "/user/identities/mock", nil)
+	req.Header.Set("X-Test-User", strconv.Itoa(int(alice.ID)))
+	if w := env.do(req); w.Code != http.StatusOK {
+		t.Fatalf("unlink status = %d: %s", w.Code, w.Body)
+	}
+	if n := env.count(t, &model.UserIdentity{}, "1 = 1"); n != 0 {
+		t.Errorf("identities after unlink = %d, want 0", n)
+	}
+	if w := env.login(t, bob.ID); w.Code != http.StatusOK {
+		t.Errorf("link to bob after unlink status = %d: %s", w.Code, w.Body)
+	}
+}
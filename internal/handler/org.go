@@ -0,0 +1,385 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"vapiv/internal/service/org"
+	"vapiv/internal/service/user"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrgHandler struct {
+	orgSvc  *org.Service
+	userSvc *user.Service
+}
+
+func NewOrgHandler(orgSvc *org.Service, userSvc *user.Service) *OrgHandler {
+	return &OrgHandler{orgSvc: orgSvc, userSvc: userSvc}
+}
+
+type CreateOrgReq struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type InviteReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin developer billing"`
+}
+
+type UpdateMemberReq struct {
+	Role string `json:"role" binding:"required,oneof=owner admin developer billing"`
+}
+
+type AcceptInvitationReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Create godoc
+// @Summary 创建组织
+// @Tags 组织
+// @Param body body CreateOrgReq true "组织信息"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs [post]
+func (h *OrgHandler) Create(c *gin.Context) {
+	var req CreateOrgReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	o, err := h.orgSvc.Create(c.Request.Context(), c.GetUint("user_id"), req.Name)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, o)
+}
+
+// List godoc
+// @Summary 我所在的组织
+// @Tags 组织
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs [get]
+func (h *OrgHandler) List(c *gin.Context) {
+	orgs, err := h.orgSvc.ListForUser(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, orgs)
+}
+
+// Get godoc
+// @Summary 组织详情（含余额）
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id} [get]
+func (h *OrgHandler) Get(c *gin.Context) {
+	orgID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	o, err := h.orgSvc.Get(c.Request.Context(), orgID)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, o)
+}
+
+// Members godoc
+// @Summary 组织成员列表
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/members [get]
+func (h *OrgHandler) Members(c *gin.Context) {
+	orgID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgSvc.Members(c.Request.Context(), orgID)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, members)
+}
+
+// Invite godoc
+// @Summary 邀请成员
+// @Description 通过邮件发送邀请码，owner/admin 可用
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Param body body InviteReq true "邀请信息"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/invitations [post]
+func (h *OrgHandler) Invite(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+
+	var req InviteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	inv, err := h.orgSvc.Invite(c.Request.Context(), uint(orgID), c.GetUint("user_id"), req.Email, req.Role)
+	if err != nil {
+		orgError(c, err)
+		return
+	}
+	response.Success(c, inv)
+}
+
+// AcceptInvitation godoc
+// @Summary 接受组织邀请
+// @Tags 组织
+// @Param body body AcceptInvitationReq true "邀请码"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/invitations/accept [post]
+func (h *OrgHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	member, err := h.orgSvc.AcceptInvitation(c.Request.Context(), c.GetUint("user_id"), req.Token)
+	if err != nil {
+		orgError(c, err)
+		return
+	}
+	response.Success(c, member)
+}
+
+// UpdateMember godoc
+// @Summary 修改成员角色
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Param user_id path int true "用户ID"
+// @Param body body UpdateMemberReq true "角色"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/members/{user_id} [put]
+func (h *OrgHandler) UpdateMember(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_user_id")
+		return
+	}
+
+	var req UpdateMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.orgSvc.UpdateMemberRole(c.Request.Context(), uint(orgID), c.GetUint("user_id"), uint(memberID), req.Role); err != nil {
+		orgError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// RemoveMember godoc
+// @Summary 移除成员或退出组织
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Param user_id path int true "用户ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/members/{user_id} [delete]
+func (h *OrgHandler) RemoveMember(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_user_id")
+		return
+	}
+
+	if err := h.orgSvc.RemoveMember(c.Request.Context(), uint(orgID), c.GetUint("user_id"), uint(memberID)); err != nil {
+		orgError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// CreateAPIKey godoc
+// @Summary 创建组织 API Key
+// @Description Key 从组织余额扣费，调用记录同时归属到创建者
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Param name query string false "Key 名称"
+// @Param expires_in_days query int false "有效期（天），为空时永不过期"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/apikeys [post]
+func (h *OrgHandler) CreateAPIKey(c *gin.Context) {
+	orgID, ok := h.authorize(c, org.RoleOwner, org.RoleAdmin, org.RoleDeveloper)
+	if !ok {
+		return
+	}
+
+	expiresAt, ok := keyExpiry(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.userSvc.CreateAPIKey(c.Request.Context(), c.GetUint("user_id"), &orgID, c.DefaultQuery("name", "default"), expiresAt, auditMeta(c))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, key)
+}
+
+// ListAPIKeys godoc
+// @Summary 组织 API Key 列表
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/apikeys [get]
+func (h *OrgHandler) ListAPIKeys(c *gin.Context) {
+	orgID, ok := h.authorize(c, org.RoleOwner, org.RoleAdmin, org.RoleDeveloper)
+	if !ok {
+		return
+	}
+
+	keys, err := h.userSvc.ListOrgAPIKeys(c.Request.Context(), orgID)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, keys)
+}
+
+// DeleteAPIKey godoc
+// @Summary 删除组织 API Key
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Param key_id path int true "Key ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/apikeys/{key_id} [delete]
+func (h *OrgHandler) DeleteAPIKey(c *gin.Context) {
+	orgID, ok := h.authorize(c, org.RoleOwner, org.RoleAdmin, org.RoleDeveloper)
+	if !ok {
+		return
+	}
+	keyID, _ := strconv.ParseUint(c.Param("key_id"), 10, 32)
+
+	if err := h.userSvc.DeleteOrgAPIKey(c.Request.Context(), orgID, uint(keyID), auditMeta(c)); err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// RotateAPIKey godoc
+// @Summary 轮换组织 API Key
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Param key_id path int true "Key ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/apikeys/{key_id}/rotate [post]
+func (h *OrgHandler) RotateAPIKey(c *gin.Context) {
+	orgID, ok := h.authorize(c, org.RoleOwner, org.RoleAdmin, org.RoleDeveloper)
+	if !ok {
+		return
+	}
+	keyID, _ := strconv.ParseUint(c.Param("key_id"), 10, 32)
+
+	key, err := h.userSvc.RotateOrgAPIKey(c.Request.Context(), orgID, uint(keyID), auditMeta(c))
+	if err != nil {
+		if errors.Is(err, user.ErrKeyNotFound) {
+			response.Error(c, response.ErrNotFound, "")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, key)
+}
+
+// Usage godoc
+// @Summary 组织调用记录
+// @Tags 组织
+// @Param id path int true "组织ID"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /orgs/{id}/usage [get]
+func (h *OrgHandler) Usage(c *gin.Context) {
+	orgID, ok := h.authorize(c, org.RoleOwner, org.RoleAdmin, org.RoleBilling)
+	if !ok {
+		return
+	}
+
+	usage, err := h.orgSvc.Usage(c.Request.Context(), orgID, 100)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, usage)
+}
+
+// authorize 解析路径中的组织ID并校验当前用户的角色，失败时已写入响应
+func (h *OrgHandler) authorize(c *gin.Context, roles ...string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "request.invalid_id")
+		return 0, false
+	}
+	if _, err := h.orgSvc.Authorize(c.Request.Context(), uint(id), c.GetUint("user_id"), roles...); err != nil {
+		orgError(c, err)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func orgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, org.ErrNotMember):
+		response.Error(c, response.ErrNotFound, "")
+	case errors.Is(err, org.ErrForbidden):
+		response.Error(c, response.ErrForbidden, "org.forbidden")
+	case errors.Is(err, org.ErrInvalidRole):
+		response.BadRequest(c, "org.invalid_role")
+	case errors.Is(err, org.ErrLastOwner):
+		response.BadRequest(c, "org.last_owner")
+	case errors.Is(err, org.ErrAlreadyMember):
+		response.BadRequest(c, "org.already_member")
+	case errors.Is(err, org.ErrInvitationInvalid):
+		response.BadRequest(c, "org.invitation_invalid")
+	case errors.Is(err, org.ErrInvalidPlan):
+		response.BadRequest(c, "org.invalid_plan")
+	default:
+		serviceError(c, err)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+
+	"vapiv/internal/service/settings"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsHandler struct {
+	svc *settings.Service
+}
+
+func NewSettingsHandler(svc *settings.Service) *SettingsHandler {
+	return &SettingsHandler{svc: svc}
+}
+
+type SetSettingReq struct {
+	// 字符串形式的值，如 200、30s、false
+	Value string `json:"value" binding:"required"`
+}
+
+// List godoc
+// @Summary 运行时设置
+// @Description 全部可在运行时修改的设置，包括类型、默认值、当前值和最后修改人
+// @Tags 管理
+// @Success 200 {object} response.Response{data=[]settings.Entry}
+// @Security BearerAuth
+// @Router /admin/settings [get]
+func (h *SettingsHandler) List(c *gin.Context) {
+	response.Success(c, h.svc.List())
+}
+
+// Set godoc
+// @Summary 修改运行时设置
+// @Description 立即对所有实例生效，修改记入审计日志
+// @Tags 管理
+// @Param key path string true "设置 key，如 ratelimit.requests"
+// @Param body body SetSettingReq true "新值"
+// @Success 200 {object} response.Response{data=settings.Entry}
+// @Security BearerAuth
+// @Router /admin/settings/{key} [put]
+func (h *SettingsHandler) Set(c *gin.Context) {
+	var req SetSettingReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	entry, err := h.svc.Set(c.Request.Context(), c.Param("key"), req.Value, auditMeta(c))
+	if err != nil {
+		h.settingsError(c, err)
+		return
+	}
+	response.Success(c, entry)
+}
+
+// Reset godoc
+// @Summary 恢复运行时设置的默认值
+// @Tags 管理
+// @Param key path string true "设置 key"
+// @Success 200 {object} response.Response{data=settings.Entry}
+// @Security BearerAuth
+// @Router /admin/settings/{key} [delete]
+func (h *SettingsHandler) Reset(c *gin.Context) {
+	entry, err := h.svc.Reset(c.Request.Context(), c.Param("key"), auditMeta(c))
+	if err != nil {
+		h.settingsError(c, err)
+		return
+	}
+	response.Success(c, entry)
+}
+
+func (h *SettingsHandler) settingsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting):
+		response.Error(c, response.ErrNotFound, "")
+	case errors.Is(err, settings.ErrInvalidValue):
+		response.BadRequest(c, err.Error())
+	default:
+		serviceError(c, err)
+	}
+}
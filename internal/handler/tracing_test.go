@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/plugin/opentelemetry/tracing"
+)
+
+// 服务方法使用请求的 context 查询数据库，查询的 span 挂在请求的 span 下
+func TestQuerySpansAreChildrenOfRequest(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	db := newTestDB(t)
+	if err := db.Use(tracing.NewPlugin(tracing.WithTracerProvider(tp), tracing.WithoutMetrics())); err != nil {
+		t.Fatal(err)
+	}
+	u := model.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&u)
+	recorder.Reset()
+
+	h := NewUserHandler(newTestUserService(t, db, user.Registration{Mode: user.RegistrationOpen}))
+	r := gin.New()
+	r.Use(otelgin.Middleware("test", otelgin.WithTracerProvider(tp)))
+	r.GET("/user/profile", withUser, h.Profile)
+
+	req := httptest.NewRequest("GET", "/user/profile", nil)
+	req.Header.Set("X-Test-User", strconv.FormatUint(uint64(u.ID), 10))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var server trace.SpanContext
+	for _, s := range recorder.Ended() {
+		if s.SpanKind() == trace.SpanKindServer {
+			server = s.SpanContext()
+		}
+	}
+	if !server.IsValid() {
+		t.Fatal("no server span recorded")
+	}
+	queries := 0
+	for _, s := range recorder.Ended() {
+		if s.SpanKind() != trace.SpanKindClient {
+			continue
+		}
+		queries++
+		if s.Parent().SpanID() != server.SpanID() {
+			t.Errorf("span %s is not a child of the request span", s.Name())
+		}
+	}
+	if queries == 0 {
+		t.Error("no query spans recorded")
+	}
+}
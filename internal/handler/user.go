@@ -1,7 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
 	"vapiv/internal/service/user"
+	"vapiv/pkg/captcha"
 	"vapiv/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -16,13 +22,17 @@ func NewUserHandler(svc *user.Service) *UserHandler {
 }
 
 type RegisterReq struct {
-	Username string `json:"username" binding:"required"`
+	// 不能包含 @，避免与邮箱登录混淆
+	Username string `json:"username" binding:"required,excludes=@"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Code     string `json:"code" binding:"required,len=6"`
+	// 注册模式为 invite 时必填
+	InviteCode string `json:"invite_code"`
 }
 
 type LoginReq struct {
+	// 用户名或邮箱
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
@@ -30,6 +40,33 @@ type LoginReq struct {
 type SendCodeReq struct {
 	Email   string `json:"email" binding:"required,email"`
 	Purpose string `json:"purpose" binding:"required,oneof=register reset"`
+	// 工作量证明解答，格式为 "<challenge id>.<nonce>"，见 GET /auth/challenge
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+type DeleteAccountReq struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6"`
+}
+
+type ChangeEmailCodeReq struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	// 工作量证明解答，与 /auth/send-code 相同
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+type ChangeEmailReq struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Code     string `json:"code" binding:"required,len=6"`
+}
+
+type LocaleReq struct {
+	Locale string `json:"locale" binding:"required,oneof=zh-CN en"`
+}
+
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
 type ResetPasswordReq struct {
@@ -40,6 +77,7 @@ type ResetPasswordReq struct {
 
 // Register godoc
 // @Summary 用户注册
+// @Description 受注册模式限制：closed 不开放注册，invite 需要邀请码，domain 只允许白名单域名的邮箱
 // @Tags 认证
 // @Param body body RegisterReq true "注册信息"
 // @Success 200 {object} response.Response
@@ -51,21 +89,56 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	if !h.svc.VerifyCode(req.Email, "register", req.Code) {
-		response.BadRequest(c, "验证码错误或已过期")
+	if !h.svc.VerifyCode(c.Request.Context(), req.Email, "register", req.Code) {
+		response.BadRequest(c, "user.code_invalid")
 		return
 	}
 
-	u, err := h.svc.Register(req.Username, req.Email, req.Password)
+	u, err := h.svc.Register(c.Request.Context(), req.Username, req.Email, req.Password, requestLocale(c), req.InviteCode, auditMeta(c))
 	if err != nil {
-		response.Error(c, 500, err.Error())
+		if !registrationError(c, err) {
+			serviceError(c, err)
+		}
 		return
 	}
 	response.Success(c, u)
 }
 
+// RegistrationMode godoc
+// @Summary 注册模式
+// @Description open、closed、invite 或 domain
+// @Tags 认证
+// @Success 200 {object} response.Response
+// @Router /auth/registration [get]
+func (h *UserHandler) RegistrationMode(c *gin.Context) {
+	mode := h.svc.RegistrationMode()
+	response.Success(c, gin.H{"mode": mode, "invite_required": mode == user.RegistrationInvite})
+}
+
+// registrationError 把注册模式相关的错误转换为响应，其他错误返回 false
+func registrationError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, user.ErrRegistrationClosed):
+		response.Error(c, response.ErrRegistrationClosed, "user.registration_closed")
+	case errors.Is(err, user.ErrEmailDomainNotAllowed):
+		response.Error(c, response.ErrRegistrationClosed, "user.domain_not_allowed")
+	case errors.Is(err, user.ErrInviteRequired):
+		response.BadRequest(c, "user.invite_required")
+	case errors.Is(err, user.ErrInvalidInvite):
+		response.BadRequest(c, "user.invite_invalid")
+	case errors.Is(err, user.ErrEmailTaken):
+		response.BadRequest(c, "user.email_taken")
+	case errors.Is(err, user.ErrUsernameTaken):
+		response.BadRequest(c, "user.username_taken")
+	default:
+		return false
+	}
+	return true
+}
+
 // Login godoc
 // @Summary 用户登录
+// @Description username 可以填写用户名或邮箱
 // @Tags 认证
 // @Param body body LoginReq true "登录信息"
 // @Success 200 {object} response.Response
@@ -77,16 +150,36 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.svc.Login(req.Username, req.Password)
+	token, err := h.svc.Login(c.Request.Context(), req.Username, req.Password, auditMeta(c))
 	if err != nil {
-		response.Error(c, 401, err.Error())
+		if !errors.Is(err, user.ErrInvalidCredentials) {
+			serviceError(c, err)
+			return
+		}
+		response.Unauthorized(c, "auth.invalid_credentials")
 		return
 	}
 	response.Success(c, gin.H{"token": token})
 }
 
+// Challenge godoc
+// @Summary 获取人机验证题目
+// @Description 工作量证明：找到 nonce 使 sha256(prefix + nonce) 至少有 difficulty 个前导零比特，发送验证码时提交 "<id>.<nonce>"。同一 IP 请求越频繁难度越高
+// @Tags 认证
+// @Success 200 {object} response.Response{data=captcha.Challenge}
+// @Router /auth/challenge [get]
+func (h *UserHandler) Challenge(c *gin.Context) {
+	challenge, err := h.svc.NewChallenge(c.Request.Context(), c.ClientIP())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, challenge)
+}
+
 // SendCode godoc
 // @Summary 发送验证码
+// @Description 需要先完成 GET /auth/challenge 的人机验证
 // @Tags 认证
 // @Param body body SendCodeReq true "请求参数"
 // @Success 200 {object} response.Response
@@ -98,17 +191,32 @@ func (h *UserHandler) SendCode(c *gin.Context) {
 		return
 	}
 
-	if req.Purpose == "register" && h.svc.EmailExists(req.Email) {
-		response.BadRequest(c, "邮箱已被注册")
+	if err := h.svc.VerifyChallenge(c.Request.Context(), req.Challenge); err != nil {
+		if errors.Is(err, captcha.ErrChallengeFailed) {
+			response.BadRequest(c, "user.challenge_failed")
+			return
+		}
+		serviceError(c, err)
 		return
 	}
-	if req.Purpose == "reset" && !h.svc.EmailExists(req.Email) {
-		response.BadRequest(c, "邮箱不存在")
+
+	if req.Purpose == "register" {
+		if err := h.svc.CheckRegistration(req.Email); err != nil {
+			registrationError(c, err)
+			return
+		}
+		if h.svc.EmailExists(c.Request.Context(), req.Email) {
+			response.BadRequest(c, "user.email_taken")
+			return
+		}
+	}
+	if req.Purpose == "reset" && !h.svc.EmailExists(c.Request.Context(), req.Email) {
+		response.BadRequest(c, "user.email_not_found")
 		return
 	}
 
-	if err := h.svc.SendCode(req.Email, req.Purpose); err != nil {
-		response.Error(c, 500, "发送验证码失败: "+err.Error())
+	if err := h.svc.SendCode(c.Request.Context(), req.Email, req.Purpose, requestLocale(c)); err != nil {
+		serviceError(c, err)
 		return
 	}
 	response.Success(c, nil)
@@ -127,14 +235,239 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	if !h.svc.VerifyCode(req.Email, "reset", req.Code) {
-		response.BadRequest(c, "验证码错误或已过期")
+	if !h.svc.VerifyCode(c.Request.Context(), req.Email, "reset", req.Code) {
+		response.BadRequest(c, "user.code_invalid")
 		return
 	}
 
-	if err := h.svc.ResetPassword(req.Email, req.NewPassword); err != nil {
-		response.Error(c, 500, "重置密码失败")
+	if err := h.svc.ResetPassword(c.Request.Context(), req.Email, req.NewPassword, auditMeta(c)); err != nil {
+		response.Error(c, response.ErrInternal, "user.reset_password_failed")
 		return
 	}
 	response.Success(c, nil)
 }
+
+// Profile godoc
+// @Summary 个人信息
+// @Tags 用户
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/profile [get]
+func (h *UserHandler) Profile(c *gin.Context) {
+	u, err := h.svc.GetProfile(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, u)
+}
+
+// SetLocale godoc
+// @Summary 设置语言
+// @Description 邮件和通知按该语言发送
+// @Tags 用户
+// @Param body body LocaleReq true "语言"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/locale [put]
+func (h *UserHandler) SetLocale(c *gin.Context) {
+	var req LocaleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.SetLocale(c.Request.Context(), c.GetUint("user_id"), req.Locale); err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ChangePassword godoc
+// @Summary 修改密码
+// @Tags 用户
+// @Param body body ChangePasswordReq true "当前密码和新密码"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/password [put]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	err := h.svc.ChangePassword(c.Request.Context(), c.GetUint("user_id"), req.OldPassword, req.NewPassword, auditMeta(c))
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			response.BadRequest(c, "user.wrong_password")
+			return
+		}
+		response.Error(c, response.ErrInternal, "user.change_password_failed")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// SendChangeEmailCode godoc
+// @Summary 发送更换邮箱验证码
+// @Description 验证码发送到新邮箱，需要先完成 GET /auth/challenge 的人机验证，每个用户每小时最多 5 次
+// @Tags 用户
+// @Param body body ChangeEmailCodeReq true "新邮箱"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/email/send-code [post]
+func (h *UserHandler) SendChangeEmailCode(c *gin.Context) {
+	var req ChangeEmailCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.VerifyChallenge(c.Request.Context(), req.Challenge); err != nil {
+		if errors.Is(err, captcha.ErrChallengeFailed) {
+			response.BadRequest(c, "user.challenge_failed")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+
+	if h.svc.EmailExists(c.Request.Context(), req.NewEmail) {
+		response.BadRequest(c, "user.email_taken")
+		return
+	}
+
+	if err := h.svc.SendChangeEmailCode(c.Request.Context(), c.GetUint("user_id"), req.NewEmail); err != nil {
+		if errors.Is(err, user.ErrEmailDomainNotAllowed) {
+			response.BadRequest(c, "user.domain_not_allowed")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ChangeEmail godoc
+// @Summary 更换邮箱
+// @Description 校验新邮箱收到的验证码后更换，并通知旧邮箱
+// @Tags 用户
+// @Param body body ChangeEmailReq true "新邮箱和验证码"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/email [put]
+func (h *UserHandler) ChangeEmail(c *gin.Context) {
+	var req ChangeEmailReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if !h.svc.VerifyChangeEmailCode(c.Request.Context(), c.GetUint("user_id"), req.NewEmail, req.Code) {
+		response.BadRequest(c, "user.code_invalid")
+		return
+	}
+
+	if err := h.svc.ChangeEmail(c.Request.Context(), c.GetUint("user_id"), req.NewEmail, auditMeta(c)); err != nil {
+		switch {
+		case errors.Is(err, user.ErrEmailDomainNotAllowed):
+			response.BadRequest(c, "user.domain_not_allowed")
+			return
+		case errors.Is(err, user.ErrEmailTaken):
+			response.BadRequest(c, "user.email_taken")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Export godoc
+// @Summary 导出个人数据
+// @Description 包含资料、API Key（仅前缀）、调用记录、组织成员关系和邀请、外部身份、通知设置和审计日志
+// @Tags 用户
+// @Param format query string false "json 或 zip" Enums(json, zip)
+// @Success 200 {object} user.ExportData
+// @Security BearerAuth
+// @Router /user/export [get]
+func (h *UserHandler) Export(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	filename := fmt.Sprintf("vapiv-export-%d-%s", userID, time.Now().Format("20060102"))
+
+	if c.Query("format") == "zip" {
+		data, err := h.svc.ExportZip(c.Request.Context(), userID)
+		if err != nil {
+			serviceError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+		c.Data(http.StatusOK, "application/zip", data)
+		return
+	}
+
+	data, err := h.svc.Export(c.Request.Context(), userID)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	c.IndentedJSON(http.StatusOK, data)
+}
+
+// SendDeleteCode godoc
+// @Summary 发送注销账号验证码
+// @Tags 用户
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/delete/send-code [post]
+func (h *UserHandler) SendDeleteCode(c *gin.Context) {
+	if err := h.svc.SendDeleteCode(c.Request.Context(), c.GetUint("user_id")); err != nil {
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// DeleteAccount godoc
+// @Summary 注销账号
+// @Description 需要密码和邮箱验证码；吊销所有 Key、匿名化调用记录，数据在保留期后彻底删除
+// @Tags 用户
+// @Param body body DeleteAccountReq true "密码和验证码"
+// @Success 200 {object} response.Response
+// @Security BearerAuth
+// @Router /user/delete [post]
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	var req DeleteAccountReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !h.svc.CheckPassword(c.Request.Context(), userID, req.Password) {
+		response.Unauthorized(c, "auth.invalid_password")
+		return
+	}
+	if !h.svc.VerifyDeleteCode(c.Request.Context(), userID, req.Code) {
+		response.BadRequest(c, "user.code_invalid")
+		return
+	}
+
+	if err := h.svc.DeleteAccount(c.Request.Context(), userID, auditMeta(c)); err != nil {
+		if errors.Is(err, user.ErrSoleOwner) {
+			response.BadRequest(c, "user.sole_owner")
+			return
+		}
+		serviceError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// requestLocale 从 lang 参数或 Accept-Language 中选出支持的语言，没有时返回空
+func requestLocale(c *gin.Context) string {
+	return response.RequestLocale(c)
+}
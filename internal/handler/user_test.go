@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/user"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func postJSON(r http.Handler, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginAccount(t *testing.T) {
+	db := newTestDB(t)
+	h := NewUserHandler(newTestUserService(t, db, user.Registration{Mode: user.RegistrationOpen}))
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	r.POST("/auth/register", h.Register)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	// 禁止 @ 之前注册的用户名
+	db.Create(&model.User{Username: "old@name", Email: "old@example.com", Password: string(hash), Status: 1})
+	db.Create(&model.User{Username: "alice", Email: "Alice@Example.com", Password: string(hash), Status: 1})
+
+	tests := []struct {
+		account  string
+		password string
+		status   int
+	}{
+		{"old@name", "secret", http.StatusOK},
+		{"alice", "secret", http.StatusOK},
+		{"alice@example.com", "secret", http.StatusOK},
+		{"ALICE@EXAMPLE.COM", "secret", http.StatusOK},
+		{"old@example.com", "secret", http.StatusOK},
+		{"alice@example.com", "wrong", http.StatusUnauthorized},
+		{"nobody@example.com", "secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := postJSON(r, "/auth/login", gin.H{"username": tt.account, "password": tt.password})
+		if w.Code != tt.status {
+			t.Errorf("login %q/%q: status %d, want %d: %s", tt.account, tt.password, w.Code, tt.status, w.Body)
+		}
+	}
+
+	w := postJSON(r, "/auth/register", gin.H{"username": "new@name", "email": "new@example.com", "password": "secret", "code": "123456"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("register with @ in username: status %d, want 400", w.Code)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	db := newTestDB(t)
+	h := NewUserHandler(newTestUserService(t, db, user.Registration{Mode: user.RegistrationOpen}))
+	r := gin.New()
+	r.GET("/user/profile", withUser, h.Profile)
+
+	u := model.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&u)
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{strconv.FormatUint(uint64(u.ID), 10), http.StatusOK},
+		{"999", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/user/profile", nil)
+		req.Header.Set("X-Test-User", tt.id)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("user %s: status %d, want %d: %s", tt.id, w.Code, tt.status, w.Body)
+		}
+	}
+}
+
+func TestChangeEmailDomain(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	svc := newTestUserService(t, db, user.Registration{Mode: user.RegistrationDomain, AllowedDomains: []string{"example.com"}})
+	r := gin.New()
+	r.POST("/user/email/send-code", withUser, NewUserHandler(svc).SendChangeEmailCode)
+
+	u := model.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&u)
+
+	sendCode := func(body gin.H) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/user/email/send-code", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", strconv.FormatUint(uint64(u.ID), 10))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := sendCode(gin.H{"new_email": "alice@other.com", "challenge": "bogus.0"}); errorCode(w) != "bad_request: "+response.T(response.DefaultLocale, "user.challenge_failed") {
+		t.Errorf("send code without solving the challenge: %d %s", w.Code, w.Body)
+	}
+	if w := sendCode(gin.H{"new_email": "alice@other.com", "challenge": solveChallenge(t, svc)}); errorCode(w) != "bad_request: "+response.T(response.DefaultLocale, "user.domain_not_allowed") {
+		t.Errorf("send code to other domain: %d %s", w.Code, w.Body)
+	}
+
+	if err := svc.ChangeEmail(ctx, u.ID, "alice@other.com", audit.Meta{}); !errors.Is(err, user.ErrEmailDomainNotAllowed) {
+		t.Errorf("change to other domain: %v", err)
+	}
+	if err := svc.ChangeEmail(ctx, u.ID, "alice2@Example.com", audit.Meta{}); err != nil {
+		t.Errorf("change within allowed domain: %v", err)
+	}
+}
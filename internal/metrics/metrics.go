@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+// Registry 只包含本服务的指标和 Go 运行时指标，不使用全局默认 registry
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vapiv_http_requests_total",
+		Help: "HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vapiv_http_request_duration_seconds",
+		Help:    "HTTP request latency by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vapiv_upstream_requests_total",
+		Help: "Outbound requests to third-party services by result.",
+	}, []string{"upstream", "op", "result"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vapiv_upstream_request_duration_seconds",
+		Help:    "Outbound request latency to third-party services.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 15},
+	}, []string{"upstream", "op"})
+
+	rateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vapiv_rate_limit_rejections_total",
+		Help: "Requests rejected by a rate limiter.",
+	}, []string{"limiter"})
+
+	creditsCharged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vapiv_credits_charged_total",
+		Help: "Credits charged for API calls by endpoint.",
+	}, []string{"endpoint"})
+
+	billingRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vapiv_billing_rejections_total",
+		Help: "API calls rejected by billing.",
+	}, []string{"endpoint", "reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		upstreamRequests, upstreamDuration,
+		rateLimitRejections, creditsCharged, billingRejections,
+	)
+}
+
+// ObserveHTTP 记录一次 HTTP 请求，route 是路由模板，未匹配的路由记为空
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// ObserveUpstream 记录一次对第三方服务的调用
+func ObserveUpstream(upstream, op string, d time.Duration, ok bool) {
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	upstreamRequests.WithLabelValues(upstream, op, result).Inc()
+	upstreamDuration.WithLabelValues(upstream, op).Observe(d.Seconds())
+}
+
+func RateLimitRejected(limiter string) {
+	rateLimitRejections.WithLabelValues(limiter).Inc()
+}
+
+func Charged(endpoint string, credits int64) {
+	creditsCharged.WithLabelValues(endpoint).Add(float64(credits))
+}
+
+func BillingRejected(endpoint, reason string) {
+	billingRejections.WithLabelValues(endpoint, reason).Inc()
+}
+
+// RegisterDB 导出连接池状态（打开、使用中、空闲、等待次数等）
+func RegisterDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "vapiv"))
+}
+
+// RegisterRedis 在每次抓取时 ping Redis，rdb 为 nil（未启用）时始终为 0
+func RegisterRedis(rdb *redis.Client) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "vapiv_redis_up",
+		Help: "Whether Redis answered a ping during the scrape.",
+	}, func() float64 {
+		if rdb == nil {
+			return 0
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if rdb.Ping(ctx).Err() != nil {
+			return 0
+		}
+		return 1
+	}))
+}
+
+// Handler 返回 /metrics 的处理函数，token 不为空时要求 Authorization: Bearer <token>
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerToken(t *testing.T) {
+	ObserveHTTP("GET", "/api/test", http.StatusOK, time.Millisecond)
+
+	tests := []struct {
+		token  string
+		header string
+		status int
+	}{
+		{"", "", http.StatusOK},
+		{"s3cret", "", http.StatusUnauthorized},
+		{"s3cret", "Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", "s3cret", http.StatusUnauthorized},
+		{"s3cret", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		Handler(tt.token).ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("token %q, header %q: status %d, want %d", tt.token, tt.header, w.Code, tt.status)
+			continue
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), `vapiv_http_requests_total{method="GET",route="/api/test",status="200"}`) {
+			t.Errorf("token %q: metrics missing the observed request", tt.token)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AccessLog 每个请求记录一条结构化日志，5xx 为 error，4xx 为 warn。
+// 需要放在 RequestID 之后；user_id、api_key_id 由后面的认证中间件写入
+func AccessLog(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("request_id", c.GetString("request_id")),
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("ip", c.ClientIP()),
+		}
+		if id := c.GetUint("user_id"); id != 0 {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(id)))
+		}
+		if id := c.GetUint("api_key_id"); id != 0 {
+			attrs = append(attrs, slog.Uint64("key_id", uint64(id)))
+		}
+		if id := c.GetUint("impersonator_id"); id != 0 {
+			attrs = append(attrs, slog.Uint64("impersonator_id", uint64(id)))
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		log.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery 捕获 panic，连同调用栈写入日志后返回 500
+func Recovery(log *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		log.ErrorContext(c.Request.Context(), "panic recovered",
+			slog.String("request_id", c.GetString("request_id")),
+			slog.Any("error", err),
+			slog.String("stack", string(debug.Stack())),
+		)
+		response.Error(c, response.ErrInternal, "")
+		c.Abort()
+	})
+}
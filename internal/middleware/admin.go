@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"vapiv/internal/model"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminMiddleware struct {
+	db *gorm.DB
+}
+
+func NewAdminMiddleware(db *gorm.DB) *AdminMiddleware {
+	return &AdminMiddleware{db: db}
+}
+
+// RequireAdmin 需要放在 JWTMiddleware 之后
+func (m *AdminMiddleware) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user model.User
+		if err := m.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+			response.Unauthorized(c, "auth.user_not_found")
+			c.Abort()
+			return
+		}
+
+		if user.Role != "admin" || user.Status != 1 {
+			response.Error(c, response.ErrAdminRequired, "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"time"
+
 	"vapiv/internal/model"
 	"vapiv/pkg/response"
 
@@ -23,20 +25,42 @@ func (m *APIKeyMiddleware) Auth() gin.HandlerFunc {
 			key = c.Query("api_key")
 		}
 		if key == "" {
-			response.Unauthorized(c, "missing api key")
+			response.Unauthorized(c, "auth.missing_api_key")
 			c.Abort()
 			return
 		}
 
 		var apiKey model.APIKey
-		if err := m.db.Where("key = ? AND status = 1", key).First(&apiKey).Error; err != nil {
-			response.Unauthorized(c, "invalid api key")
+		err := m.db.WithContext(c.Request.Context()).
+			Where("key = ? AND status = 1", key).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			First(&apiKey).Error
+		if err != nil {
+			response.Unauthorized(c, "auth.invalid_api_key")
 			c.Abort()
 			return
 		}
 
+		// 组织 Key 要求创建者仍是成员，且组织未被禁用
+		if apiKey.OrgID != nil {
+			var count int64
+			err := m.db.WithContext(c.Request.Context()).Model(&model.OrgMember{}).
+				Joins("JOIN organizations ON organizations.id = org_members.org_id").
+				Where("org_members.org_id = ? AND org_members.user_id = ?", *apiKey.OrgID, apiKey.UserID).
+				Where("organizations.status = 1 AND organizations.deleted_at IS NULL").
+				Count(&count).Error
+			if err != nil || count == 0 {
+				response.Unauthorized(c, "auth.invalid_api_key")
+				c.Abort()
+				return
+			}
+		}
+
 		c.Set("user_id", apiKey.UserID)
 		c.Set("api_key_id", apiKey.ID)
+		if apiKey.OrgID != nil {
+			c.Set("org_id", *apiKey.OrgID)
+		}
 		c.Next()
 	}
 }
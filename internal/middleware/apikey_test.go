@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vapiv/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyExpiry(t *testing.T) {
+	db := newTestDB(t, &model.APIKey{})
+	r := gin.New()
+	r.GET("/api", NewAPIKeyMiddleware(db).Auth(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	db.Create(&model.APIKey{UserID: 1, Key: "never"})
+	db.Create(&model.APIKey{UserID: 1, Key: "valid", ExpiresAt: &future})
+	db.Create(&model.APIKey{UserID: 1, Key: "expired", ExpiresAt: &past})
+
+	for key, want := range map[string]int{
+		"never":   http.StatusNoContent,
+		"valid":   http.StatusNoContent,
+		"expired": http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest("GET", "/api", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: status %d, want %d", key, w.Code, want)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package middleware
 
 import (
+	"context"
+
+	"vapiv/internal/metrics"
 	"vapiv/internal/model"
+	"vapiv/internal/service/endpoint"
+	"vapiv/internal/service/notification"
+	"vapiv/internal/service/settings"
 	"vapiv/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -9,19 +15,24 @@ import (
 )
 
 type BillingMiddleware struct {
-	db *gorm.DB
+	db          *gorm.DB
+	endpointSvc *endpoint.Service
+	notifySvc   *notification.Service
+	// 提醒阈值从 billing.low_balance_threshold 读取
+	settings *settings.Service
 }
 
-func NewBillingMiddleware(db *gorm.DB) *BillingMiddleware {
-	return &BillingMiddleware{db: db}
+func NewBillingMiddleware(db *gorm.DB, endpointSvc *endpoint.Service, notifySvc *notification.Service, settings *settings.Service) *BillingMiddleware {
+	return &BillingMiddleware{db: db, endpointSvc: endpointSvc, notifySvc: notifySvc, settings: settings}
 }
 
+// Charge 需要放在 APIKeyMiddleware 之后；组织的 Key 从组织余额扣费
 func (m *BillingMiddleware) Charge() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		endpoint := c.FullPath()
+		path := c.FullPath()
 
-		var apiCfg model.APIConfig
-		if err := m.db.Where("endpoint = ?", endpoint).First(&apiCfg).Error; err != nil {
+		apiCfg, ok := m.endpointSvc.Get(path)
+		if !ok {
 			c.Next()
 			return
 		}
@@ -33,28 +44,71 @@ func (m *BillingMiddleware) Charge() gin.HandlerFunc {
 
 		userID, exists := c.Get("user_id")
 		if !exists {
-			response.Unauthorized(c, "authentication required")
+			response.Unauthorized(c, "auth.required")
 			c.Abort()
 			return
 		}
 
+		var orgID *uint
+		if v, ok := c.Get("org_id"); ok {
+			id := v.(uint)
+			orgID = &id
+		}
+
+		db := m.db.WithContext(c.Request.Context())
 		if apiCfg.Cost > 0 {
-			var user model.User
-			if err := m.db.First(&user, userID).Error; err != nil {
-				response.Error(c, 500, "user not found")
+			var result *gorm.DB
+			if orgID != nil {
+				result = db.Model(&model.Organization{}).
+					Where("id = ? AND balance >= ?", *orgID, apiCfg.Cost).
+					Update("balance", gorm.Expr("balance - ?", apiCfg.Cost))
+			} else {
+				result = db.Model(&model.User{}).
+					Where("id = ? AND balance >= ?", userID, apiCfg.Cost).
+					Update("balance", gorm.Expr("balance - ?", apiCfg.Cost))
+			}
+			if result.Error != nil {
+				response.Error(c, response.ErrInternal, "billing.charge_failed")
 				c.Abort()
 				return
 			}
-
-			if user.Balance < apiCfg.Cost {
-				response.Error(c, 402, "insufficient balance")
+			if result.RowsAffected == 0 {
+				metrics.BillingRejected(path, "insufficient_balance")
+				response.Error(c, response.ErrInsufficientBalance, "")
 				c.Abort()
 				return
 			}
-
-			m.db.Model(&user).Update("balance", gorm.Expr("balance - ?", apiCfg.Cost))
+			metrics.Charged(path, apiCfg.Cost)
+			if orgID == nil {
+				m.checkLowBalance(c.Request.Context(), userID.(uint), apiCfg.Cost)
+			}
 		}
 
+		// 调用同时记在 Key 和创建该 Key 的成员名下
+		db.Create(&model.APIUsage{
+			UserID:   userID.(uint),
+			APIKeyID: c.GetUint("api_key_id"),
+			OrgID:    orgID,
+			Endpoint: path,
+			Cost:     apiCfg.Cost,
+			IP:       c.ClientIP(),
+		})
+
 		c.Next()
 	}
 }
+
+// checkLowBalance 只在这次扣费让余额跨过阈值时提醒一次
+func (m *BillingMiddleware) checkLowBalance(ctx context.Context, userID uint, cost int64) {
+	threshold := m.settings.Int(settings.LowBalanceThreshold)
+	if threshold <= 0 {
+		return
+	}
+	var balance int64
+	if err := m.db.WithContext(ctx).Model(&model.User{}).Select("balance").Where("id = ?", userID).Scan(&balance).Error; err != nil {
+		return
+	}
+	if balance < threshold && balance+cost >= threshold {
+		m.notifySvc.NotifyAsync(ctx, userID, notification.LowBalanceMessage(balance, threshold))
+	}
+}
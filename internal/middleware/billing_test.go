@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/endpoint"
+	"vapiv/internal/service/notification"
+	"vapiv/internal/service/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOrgKeyBilling(t *testing.T) {
+	db := newTestDB(t, &model.User{}, &model.APIKey{}, &model.APIUsage{}, &model.APIConfig{},
+		&model.Organization{}, &model.OrgMember{}, &model.Setting{})
+
+	cfg := model.APIConfig{Endpoint: "/api/paid", Method: "GET", Name: "paid", Cost: 10}
+	db.Create(&cfg)
+	db.Model(&cfg).Update("is_public", false)
+	endpointSvc := endpoint.NewService(db, nil)
+	if err := endpointSvc.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	billing := NewBillingMiddleware(db, endpointSvc, notification.NewService(db), settings.NewService(db, nil, nil))
+
+	r := gin.New()
+	r.GET("/api/paid", NewAPIKeyMiddleware(db).Auth(), billing.Charge(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	call := func(key string) int {
+		req := httptest.NewRequest("GET", "/api/paid", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	member := model.User{Username: "dev", Email: "dev@example.com", Balance: 100}
+	db.Create(&member)
+	org := model.Organization{Name: "team", Balance: 15}
+	db.Create(&org)
+	db.Create(&model.OrgMember{OrgID: org.ID, UserID: member.ID, Role: "developer"})
+	db.Create(&model.APIKey{UserID: member.ID, OrgID: &org.ID, Key: "org-key"})
+
+	if code := call("org-key"); code != http.StatusNoContent {
+		t.Fatalf("first call: status %d", code)
+	}
+	db.First(&org, org.ID)
+	db.First(&member, member.ID)
+	if org.Balance != 5 || member.Balance != 100 {
+		t.Errorf("org balance %d, member balance %d; want 5 and 100", org.Balance, member.Balance)
+	}
+	var usage model.APIUsage
+	db.First(&usage)
+	if usage.UserID != member.ID || usage.OrgID == nil || *usage.OrgID != org.ID {
+		t.Errorf("usage attributed to user %d org %v", usage.UserID, usage.OrgID)
+	}
+
+	if code := call("org-key"); code != http.StatusPaymentRequired {
+		t.Errorf("insufficient org balance: status %d", code)
+	}
+
+	db.Model(&org).Update("balance", 100)
+	db.Model(&org).Update("status", 0)
+	if code := call("org-key"); code != http.StatusUnauthorized {
+		t.Errorf("disabled org: status %d", code)
+	}
+
+	db.Model(&org).Update("status", 1)
+	db.Where("user_id = ?", member.ID).Delete(&model.OrgMember{})
+	if code := call("org-key"); code != http.StatusUnauthorized {
+		t.Errorf("removed member: status %d", code)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"vapiv/internal/service/endpoint"
+	"vapiv/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EndpointMiddleware struct {
+	svc *endpoint.Service
+}
+
+func NewEndpointMiddleware(svc *endpoint.Service) *EndpointMiddleware {
+	return &EndpointMiddleware{svc: svc}
+}
+
+// Online 拦截被管理员下线的接口
+func (m *EndpointMiddleware) Online() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg, ok := m.svc.Get(c.FullPath())
+		if ok && cfg.Status == endpoint.StatusOffline {
+			response.Error(c, response.ErrEndpointOffline, "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,49 +1,130 @@
 package middleware
 
 import (
+	"strconv"
 	"strings"
 
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
 	"vapiv/pkg/response"
+	"vapiv/pkg/token"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 type JWTMiddleware struct {
-	secret string
+	keys     *token.KeySet
+	db       *gorm.DB
+	auditSvc *audit.Service
 }
 
-func NewJWTMiddleware(secret string) *JWTMiddleware {
-	return &JWTMiddleware{secret: secret}
+func NewJWTMiddleware(keys *token.KeySet, db *gorm.DB, auditSvc *audit.Service) *JWTMiddleware {
+	return &JWTMiddleware{keys: keys, db: db, auditSvc: auditSvc}
 }
 
 func (m *JWTMiddleware) Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
 		if auth == "" {
-			response.Unauthorized(c, "missing authorization header")
+			response.Unauthorized(c, "auth.missing_header")
 			c.Abort()
 			return
 		}
 
 		parts := strings.SplitN(auth, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			response.Unauthorized(c, "invalid authorization format")
+			response.Unauthorized(c, "auth.invalid_format")
 			c.Abort()
 			return
 		}
 
-		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
-			return []byte(m.secret), nil
-		})
-		if err != nil || !token.Valid {
-			response.Unauthorized(c, "invalid token")
+		claims, err := m.keys.Parse(parts[1])
+		if err != nil {
+			response.Unauthorized(c, "auth.invalid_token")
 			c.Abort()
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", uint(claims["user_id"].(float64)))
+		userID, ok := claims["user_id"].(float64)
+		if !ok {
+			response.Unauthorized(c, "auth.invalid_token")
+			c.Abort()
+			return
+		}
+		// 注销或禁用后，已签发但未过期的 token 立即失效
+		var user model.User
+		if err := m.db.WithContext(c.Request.Context()).Select("id", "status").First(&user, uint(userID)).Error; err != nil {
+			response.Unauthorized(c, "auth.user_not_found")
+			c.Abort()
+			return
+		}
+		if user.Status != 1 {
+			response.Unauthorized(c, "auth.user_disabled")
+			c.Abort()
+			return
+		}
+		c.Set("user_id", uint(userID))
+
+		if _, ok := claims[token.ClaimActor]; !ok {
+			c.Next()
+			return
+		}
+		imp, ok := token.ParseImpersonation(claims)
+		if !ok {
+			response.Unauthorized(c, "auth.invalid_token")
+			c.Abort()
+			return
+		}
+		// 发起模拟的管理员被禁用、注销或取消管理员角色后，token 立即失效
+		var actor model.User
+		err = m.db.WithContext(c.Request.Context()).Select("id", "role", "status").First(&actor, imp.ActorID).Error
+		if err != nil || actor.Status != 1 || actor.Role != "admin" {
+			response.Unauthorized(c, "auth.impersonator_revoked")
+			c.Abort()
+			return
+		}
+		m.impersonated(c, uint(userID), imp)
+	}
+}
+
+// impersonated 处理模拟登录 token：只读 token 只允许安全方法，
+// 每个请求都同时记录到管理员和被模拟用户名下
+func (m *JWTMiddleware) impersonated(c *gin.Context, userID uint, imp *token.Impersonation) {
+	c.Set("impersonator_id", imp.ActorID)
+	c.Header("X-Impersonated-By", strconv.FormatUint(uint64(imp.ActorID), 10))
+
+	switch c.Request.Method {
+	case "GET", "HEAD", "OPTIONS":
+		c.Next()
+	default:
+		if imp.Write {
+			c.Next()
+		} else {
+			response.Error(c, response.ErrImpersonationForbidden, "impersonation.read_only")
+			c.Abort()
+		}
+	}
+
+	m.auditSvc.Record(c.Request.Context(), audit.Meta{
+		ActorID:   imp.ActorID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}, userID, audit.ActionImpersonatedRequest, nil, map[string]interface{}{
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+		"status": c.Writer.Status(),
+	})
+}
+
+// NoImpersonation 拒绝模拟登录 token，用于密码、账号和计费相关的路由，需要放在 Auth 之后
+func (m *JWTMiddleware) NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("impersonator_id"); ok {
+			response.Error(c, response.ErrImpersonationForbidden, "")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
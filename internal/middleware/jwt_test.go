@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/pkg/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthRejectsDeletedAndDisabledUsers(t *testing.T) {
+	db := newTestDB(t, &model.User{}, &model.AuditEvent{})
+
+	keys, err := token.NewKeySet(nil, nil, "vapiv", "vapiv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/me", NewJWTMiddleware(keys, db, audit.NewService(db)).Auth(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	active := model.User{Username: "active", Email: "active@example.com"}
+	deleted := model.User{Username: "deleted", Email: "deleted@example.com"}
+	disabled := model.User{Username: "disabled", Email: "disabled@example.com"}
+	for _, u := range []*model.User{&active, &deleted, &disabled} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Delete(&deleted)
+	db.Model(&disabled).Update("status", 0)
+
+	tests := []struct {
+		user   model.User
+		status int
+	}{
+		{active, http.StatusNoContent},
+		{deleted, http.StatusUnauthorized},
+		{disabled, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		tok, err := keys.Sign(jwt.MapClaims{"user_id": tt.user.ID, "exp": time.Now().Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.user.Username, w.Code, tt.status)
+		}
+	}
+}
+
+func TestAuthRejectsRevokedImpersonator(t *testing.T) {
+	db := newTestDB(t, &model.User{}, &model.AuditEvent{})
+
+	keys, err := token.NewKeySet(nil, nil, "vapiv", "vapiv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/me", NewJWTMiddleware(keys, db, audit.NewService(db)).Auth(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	target := model.User{Username: "target", Email: "target@example.com"}
+	admin := model.User{Username: "admin", Email: "admin@example.com", Role: "admin"}
+	demoted := model.User{Username: "demoted", Email: "demoted@example.com", Role: "admin"}
+	disabled := model.User{Username: "disabled", Email: "disabled@example.com", Role: "admin"}
+	deleted := model.User{Username: "deleted", Email: "deleted@example.com", Role: "admin"}
+	for _, u := range []*model.User{&target, &admin, &demoted, &disabled, &deleted} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Model(&demoted).Update("role", "user")
+	db.Model(&disabled).Update("status", 0)
+	db.Delete(&deleted)
+
+	tests := []struct {
+		actor  model.User
+		status int
+	}{
+		{admin, http.StatusNoContent},
+		{demoted, http.StatusUnauthorized},
+		{disabled, http.StatusUnauthorized},
+		{deleted, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		tok, err := keys.Sign(jwt.MapClaims{
+			"user_id":        target.ID,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			token.ClaimActor: map[string]interface{}{"sub": strconv.FormatUint(uint64(tt.actor.ID), 10)},
+			token.ClaimScope: token.ScopeRead,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("impersonated by %s: status %d, want %d", tt.actor.Username, w.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"time"
+
+	"vapiv/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 按路由模板和状态码统计请求数和耗时，未匹配路由的请求 route 为空，避免标签过多
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTP(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vapiv/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsUsesRouteTemplate(t *testing.T) {
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/metrics-test/:id", func(c *gin.Context) { c.Status(204) })
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test-missing/3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler("").ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, `vapiv_http_requests_total{method="GET",route="/metrics-test/:id",status="204"} 2`) {
+		t.Error("requests are not counted under the route template")
+	}
+	if !strings.Contains(body, `vapiv_http_requests_total{method="GET",route="",status="404"}`) {
+		t.Error("unmatched request is not counted with an empty route")
+	}
+	if strings.Contains(body, "/metrics-test/1") || strings.Contains(body, "metrics-test-missing") {
+		t.Error("raw request path used as a label")
+	}
+}
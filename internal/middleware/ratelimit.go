@@ -1,41 +1,104 @@
 package middleware
 
 import (
-	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"vapiv/internal/metrics"
+	"vapiv/internal/service/settings"
 	"vapiv/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 )
 
+// RateLimiter 按 IP 限流，开关、次数和窗口从运行时设置中读取，修改后立即生效；
+// 窗口的修改对已经开始计数的 IP 在下一个窗口生效
 type RateLimiter struct {
-	redis  *redis.Client
-	limit  int
-	window time.Duration
+	redis    *redis.Client
+	settings *settings.Service
+
+	// 没有 Redis 时 PerUser 在本实例内计数
+	mu    sync.Mutex
+	local map[string]localWindow
+}
+
+type localWindow struct {
+	count   int64
+	expires time.Time
 }
 
-func NewRateLimiter(redis *redis.Client, limit int, window time.Duration) *RateLimiter {
-	return &RateLimiter{redis: redis, limit: limit, window: window}
+// NewRateLimiter 的 redis 可以为空，此时只能使用 PerUser
+func NewRateLimiter(redis *redis.Client, settings *settings.Service) *RateLimiter {
+	return &RateLimiter{redis: redis, settings: settings, local: map[string]localWindow{}}
 }
 
 func (r *RateLimiter) Limit() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !r.settings.Bool(settings.RateLimitEnabled) {
+			c.Next()
+			return
+		}
 		key := fmt.Sprintf("rate:%s", c.ClientIP())
-		ctx := context.Background()
+		ctx := c.Request.Context()
 
 		count, _ := r.redis.Incr(ctx, key).Result()
 		if count == 1 {
-			r.redis.Expire(ctx, key, r.window)
+			r.redis.Expire(ctx, key, r.settings.Duration(settings.RateLimitWindow))
 		}
 
-		if count > int64(r.limit) {
-			response.Error(c, 429, "rate limit exceeded")
+		if count > r.settings.Int(settings.RateLimitRequests) {
+			metrics.RateLimitRejected("ip")
+			response.Error(c, response.ErrRateLimited, "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// PerUser 按当前用户限制发送邮件、测试通知等会产生外发消息的操作，需要放在 JWT 中间件之后。
+// 次数和窗口固定，不受 ratelimit.enabled 影响；没有 Redis 时每个实例单独计数
+func (r *RateLimiter) PerUser(name string, limit int64, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := fmt.Sprintf("rate:%s:%d", name, c.GetUint("user_id"))
+		if r.incr(c, key, window) > limit {
+			metrics.RateLimitRejected(name)
+			response.Error(c, response.ErrRateLimited, "")
 			c.Abort()
 			return
 		}
 		c.Next()
 	}
 }
+
+func (r *RateLimiter) incr(c *gin.Context, key string, window time.Duration) int64 {
+	if r.redis != nil {
+		ctx := c.Request.Context()
+		count, err := r.redis.Incr(ctx, key).Result()
+		if err == nil {
+			if count == 1 {
+				r.redis.Expire(ctx, key, window)
+			}
+			return count
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	w := r.local[key]
+	if now.After(w.expires) {
+		// 顺便清理过期的计数
+		for k, v := range r.local {
+			if now.After(v.expires) {
+				delete(r.local, k)
+			}
+		}
+		w = localWindow{expires: now.Add(window)}
+	}
+	w.count++
+	r.local[key] = w
+	return w.count
+}
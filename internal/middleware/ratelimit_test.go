@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPerUser(t *testing.T) {
+	mr := miniredis.RunT(t)
+	for name, rdb := range map[string]*redis.Client{
+		"redis": redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		"local": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/test", func(c *gin.Context) {
+				c.Set("user_id", uint(len(c.GetHeader("X-User"))))
+			}, NewRateLimiter(rdb, nil).PerUser("test", 2, time.Minute), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+			call := func(user string) int {
+				req := httptest.NewRequest("POST", "/test", nil)
+				req.Header.Set("X-User", user)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+				if code := call("a"); code != want {
+					t.Errorf("call %d: status %d, want %d", i+1, code, want)
+				}
+			}
+			if code := call("bb"); code != http.StatusNoContent {
+				t.Errorf("other user: status %d", code)
+			}
+			if name == "redis" {
+				mr.FastForward(time.Minute)
+				if code := call("a"); code != http.StatusNoContent {
+					t.Errorf("after window: status %d", code)
+				}
+			}
+		})
+	}
+}
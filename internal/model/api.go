@@ -6,6 +6,7 @@ type APIUsage struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	UserID    uint      `gorm:"index" json:"user_id"`
 	APIKeyID  uint      `gorm:"index" json:"api_key_id"`
+	OrgID     *uint     `gorm:"index" json:"org_id,omitempty"`
 	Endpoint  string    `gorm:"size:200;index" json:"endpoint"`
 	Cost      int64     `gorm:"default:0" json:"cost"`
 	IP        string    `gorm:"size:50" json:"ip"`
@@ -13,10 +14,14 @@ type APIUsage struct {
 }
 
 type APIConfig struct {
-	ID       uint   `gorm:"primarykey" json:"id"`
-	Endpoint string `gorm:"uniqueIndex;size:200" json:"endpoint"`
-	Name     string `gorm:"size:100" json:"name"`
-	Cost     int64  `gorm:"default:0" json:"cost"`
-	IsPublic bool   `gorm:"default:true" json:"is_public"`
-	Status   int    `gorm:"default:1" json:"status"`
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Endpoint  string    `gorm:"uniqueIndex;size:200" json:"endpoint"`
+	Method    string    `gorm:"size:10" json:"method"`
+	Name      string    `gorm:"size:100" json:"name"`
+	Cost      int64     `gorm:"default:0" json:"cost"`
+	IsPublic  bool      `gorm:"default:true" json:"is_public"`
+	Status    int       `gorm:"default:1" json:"status"`
+	Tags      string    `gorm:"size:200" json:"tags"`
+	RateTier  string    `gorm:"size:20;default:standard" json:"rate_tier"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// AuditEvent 只追加不修改；UserID 是被操作的账号，ActorID 是实际操作人
+type AuditEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ActorID   uint      `gorm:"index" json:"actor_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Action    string    `gorm:"size:50;index" json:"action"`
+	IP        string    `gorm:"size:50" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Before    string    `gorm:"type:text" json:"before,omitempty"`
+	After     string    `gorm:"type:text" json:"after,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
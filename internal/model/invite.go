@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// InviteCode 邀请码注册模式下使用，MaxUses 为 0 表示不限次数
+type InviteCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	Code      string     `gorm:"uniqueIndex;size:32" json:"code"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	Note      string     `gorm:"size:255" json:"note"`
+	CreatedBy uint       `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// NotificationSetting 是用户对某类事件选择的一个通知渠道，
+// 某类事件没有任何设置时默认发送到账号邮箱
+type NotificationSetting struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_notification_setting" json:"user_id"`
+	Event     string    `gorm:"size:30;uniqueIndex:idx_notification_setting" json:"event"`
+	Channel   string    `gorm:"size:20;uniqueIndex:idx_notification_setting" json:"channel"`
+	Target    string    `gorm:"size:500" json:"target"`
+	Secret    string    `gorm:"size:255" json:"-"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Organization struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	Name      string         `gorm:"size:100" json:"name"`
+	Balance   int64          `gorm:"default:0" json:"balance"`
+	Plan      string         `gorm:"size:20;default:free" json:"plan"`
+	Status    int            `gorm:"default:1" json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type OrgMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	OrgID     uint      `gorm:"uniqueIndex:idx_org_member" json:"org_id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_org_member;index" json:"user_id"`
+	Role      string    `gorm:"size:20" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrgInvitation struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	OrgID      uint       `gorm:"index" json:"org_id"`
+	Email      string     `gorm:"size:100;index" json:"email"`
+	Role       string     `gorm:"size:20" json:"role"`
+	Token      string     `gorm:"uniqueIndex;size:64" json:"-"`
+	InvitedBy  uint       `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
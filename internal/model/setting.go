@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Setting 是运行时设置中被修改过的值，没有记录的设置使用代码中的默认值
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:64" json:"key"`
+	Value     string    `gorm:"size:255" json:"value"`
+	UpdatedBy uint      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -12,18 +12,34 @@ type User struct {
 	Email     string         `gorm:"uniqueIndex;size:100" json:"email"`
 	Password  string         `gorm:"size:255" json:"-"`
 	Balance   int64          `gorm:"default:0" json:"balance"`
+	Role      string         `gorm:"size:20;default:user" json:"role"`
+	Locale    string         `gorm:"size:10;default:zh-CN" json:"locale"`
 	Status    int            `gorm:"default:1" json:"status"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// APIKey 的 ExpiresAt 为空表示永不过期，ExpiryNotifiedAt 记录已发送的到期提醒
 type APIKey struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	UserID    uint           `gorm:"index" json:"user_id"`
-	Key       string         `gorm:"uniqueIndex;size:128" json:"key"`
-	Name      string         `gorm:"size:100" json:"name"`
-	Status    int            `gorm:"default:1" json:"status"`
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primarykey" json:"id"`
+	UserID           uint           `gorm:"index" json:"user_id"`
+	OrgID            *uint          `gorm:"index" json:"org_id,omitempty"`
+	Key              string         `gorm:"uniqueIndex;size:128" json:"key"`
+	Name             string         `gorm:"size:100" json:"name"`
+	Status           int            `gorm:"default:1" json:"status"`
+	ExpiresAt        *time.Time     `gorm:"index" json:"expires_at,omitempty"`
+	ExpiryNotifiedAt *time.Time     `json:"-"`
+	CreatedAt        time.Time      `json:"created_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// UserIdentity 把外部身份提供方的账号关联到用户
+type UserIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Provider  string    `gorm:"size:50;uniqueIndex:idx_identity" json:"provider"`
+	Subject   string    `gorm:"size:255;uniqueIndex:idx_identity" json:"subject"`
+	Email     string    `gorm:"size:100" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
 }
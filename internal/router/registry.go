@@ -0,0 +1,29 @@
+package router
+
+import (
+	"path"
+
+	"vapiv/internal/service/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registry 记录通过它注册的 API 路由，启动时同步到 APIConfig
+type registry struct {
+	routes []endpoint.Route
+}
+
+func (r *registry) handle(g *gin.RouterGroup, method, relativePath string, route endpoint.Route, handlers ...gin.HandlerFunc) {
+	route.Method = method
+	route.Path = path.Join(g.BasePath(), relativePath)
+	r.routes = append(r.routes, route)
+	g.Handle(method, relativePath, handlers...)
+}
+
+func (r *registry) GET(g *gin.RouterGroup, relativePath string, route endpoint.Route, handlers ...gin.HandlerFunc) {
+	r.handle(g, "GET", relativePath, route, handlers...)
+}
+
+func (r *registry) POST(g *gin.RouterGroup, relativePath string, route endpoint.Route, handlers ...gin.HandlerFunc) {
+	r.handle(g, "POST", relativePath, route, handlers...)
+}
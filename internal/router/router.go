@@ -1,90 +1,348 @@
 package router
 
 import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"vapiv/docs"
 	"vapiv/internal/config"
 	"vapiv/internal/handler"
+	"vapiv/internal/metrics"
 	"vapiv/internal/middleware"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/endpoint"
+	"vapiv/internal/service/health"
+	"vapiv/internal/service/notification"
+	"vapiv/internal/service/org"
+	"vapiv/internal/service/settings"
 	"vapiv/internal/service/user"
 	"vapiv/pkg/captcha"
 	"vapiv/pkg/email"
+	"vapiv/pkg/notify"
+	"vapiv/pkg/oidc"
+	"vapiv/pkg/response"
+	"vapiv/pkg/token"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"gorm.io/gorm"
 )
 
-func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
-	r := gin.Default()
+// Setup 创建路由并启动后台任务。返回的 stop 在 HTTP 服务停止后调用，
+// 停止后台任务并等待异步通知和邮件发送完成
+func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) (*gin.Engine, func(context.Context) error) {
+	r := gin.New()
+	// 限流和人机验证按 ClientIP 计数，不能采信任意客户端伪造的 X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES:", err)
+	}
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName), middleware.RequestID(), middleware.AccessLog(slog.Default()), middleware.Metrics(), middleware.Recovery(slog.Default()))
+	response.SetLegacyStatus(cfg.Server.LegacyStatus)
 
-	// 中间件
-	jwtMw := middleware.NewJWTMiddleware(cfg.JWT.Secret)
-	apiKeyMw := middleware.NewAPIKeyMiddleware(db)
+	keys, err := token.LoadKeySet(cfg.JWT.PrivateKeyFile, cfg.JWT.PublicKeyFiles, cfg.JWT.Issuer, cfg.JWT.Audience)
+	if err != nil {
+		log.Fatal("failed to load jwt keys:", err)
+	}
+	if cfg.JWT.PrivateKeyFile == "" {
+		slog.Warn("JWT_PRIVATE_KEY_FILE not set, using an ephemeral signing key; tokens are lost on restart and not shared between replicas")
+	}
 
 	// 服务
-	emailSvc := email.NewService(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	auditSvc := audit.NewService(db)
+	mailSender, err := email.NewSender(email.Config{
+		Backend:    cfg.SMTP.Backend,
+		From:       cfg.SMTP.From,
+		Host:       cfg.SMTP.Host,
+		Port:       cfg.SMTP.Port,
+		Username:   cfg.SMTP.Username,
+		Password:   cfg.SMTP.Password,
+		Security:   cfg.SMTP.Security,
+		Auth:       cfg.SMTP.Auth,
+		PoolSize:   cfg.SMTP.PoolSize,
+		CaptureDir: cfg.SMTP.CaptureDir,
+	})
+	if err != nil {
+		log.Fatal("failed to configure email:", err)
+	}
+	mailQueue := email.NewQueue(rdb, mailSender)
+	channels := []notify.Channel{
+		notify.NewEmailChannel(mailQueue),
+		notify.NewWebhookChannel(nil),
+		notify.NewDingTalkChannel(nil),
+		notify.NewWeComChannel(nil),
+		notify.NewFeishuChannel(nil),
+	}
+	switch cfg.Notify.SMSProvider {
+	case "":
+	case "mock":
+		channels = append(channels, notify.NewSMSChannel(notify.NewMockSMSProvider()))
+	default:
+		slog.Warn("unknown SMS_PROVIDER, sms channel disabled", "provider", cfg.Notify.SMSProvider)
+	}
+	notifySvc := notification.NewService(db, channels...)
 	captchaSvc := captcha.NewService(rdb)
-	userSvc := user.NewService(db, cfg.JWT.Secret, cfg.JWT.ExpireHour, emailSvc, captchaSvc)
+	userSvc := user.NewService(db, keys, cfg.JWT.ExpireHour, notifySvc, captchaSvc, auditSvc, user.Registration{
+		Mode:           cfg.Account.RegistrationMode,
+		AllowedDomains: cfg.Account.RegistrationDomains,
+	})
+	endpointSvc := endpoint.NewService(db, rdb)
+	orgSvc := org.NewService(db, notifySvc)
+	settingsSvc := settings.NewService(db, rdb, auditSvc)
+	if err := settingsSvc.SetDefault(settings.LowBalanceThreshold, strconv.FormatInt(cfg.Notify.LowBalanceThreshold, 10)); err != nil {
+		log.Fatal("invalid LOW_BALANCE_THRESHOLD:", err)
+	}
+	if err := settingsSvc.Load(context.Background()); err != nil {
+		slog.Warn("failed to load runtime settings, using defaults", "error", err)
+	}
+
+	// 中间件
+	jwtMw := middleware.NewJWTMiddleware(keys, db, auditSvc)
+	apiKeyMw := middleware.NewAPIKeyMiddleware(db)
+	adminMw := middleware.NewAdminMiddleware(db)
+	endpointMw := middleware.NewEndpointMiddleware(endpointSvc)
+	billingMw := middleware.NewBillingMiddleware(db, endpointSvc, notifySvc, settingsSvc)
+	rateLimiter := middleware.NewRateLimiter(rdb, settingsSvc)
 
 	// Handler
 	userH := handler.NewUserHandler(userSvc)
 	apiKeyH := handler.NewAPIKeyHandler(userSvc)
-	coreH := handler.NewCoreHandler()
-	contentH := handler.NewContentHandler()
+	coreH := handler.NewCoreHandler(settingsSvc)
+	contentH := handler.NewContentHandler(settingsSvc)
+	adminH := handler.NewAdminHandler(endpointSvc, userSvc, orgSvc, auditSvc)
+	auditH := handler.NewAuditHandler(auditSvc)
+	settingsH := handler.NewSettingsHandler(settingsSvc)
+	notificationH := handler.NewNotificationHandler(notifySvc)
+	orgH := handler.NewOrgHandler(orgSvc, userSvc)
+
+	var providers []*oidc.Provider
+	for _, p := range cfg.OIDC.Providers {
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil))
+	}
+	oidcH := handler.NewOIDCHandler(userSvc, providers, cfg.JWT.Secret, cfg.OIDC.SuccessURL)
+
+	catalog, err := endpoint.NewCatalog(endpointSvc, docs.SwaggerInfo.ReadDoc())
+	if err != nil {
+		slog.Warn("failed to parse swagger doc", "error", err)
+		catalog, _ = endpoint.NewCatalog(endpointSvc, "{}")
+	}
+	catalogH := handler.NewCatalogHandler(catalog)
 
 	// 公共路由
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+	healthH := handler.NewHealthHandler(health.NewService(db, rdb, cfg.Redis.Required))
+	r.GET("/livez", healthH.Livez)
+	r.GET("/readyz", healthH.Readyz)
+	// 旧的存活检查地址
+	r.GET("/health", healthH.Livez)
+
+	r.GET("/catalog", catalogH.List)
+	r.GET("/catalog/errors", catalogH.Errors)
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, keys.JWKS())
 	})
 
+	// 内存收件箱，只用于开发和集成测试
+	if inbox, ok := mailSender.(*email.MemorySender); ok {
+		if cfg.Server.Mode == gin.ReleaseMode {
+			slog.Warn("EMAIL_BACKEND=memory in release mode, /dev/mail is disabled and mail is discarded")
+		} else {
+			devMailH := handler.NewDevMailHandler(inbox)
+			r.GET("/dev/mail", devMailH.List)
+			r.GET("/dev/mail/:id", devMailH.Get)
+			r.DELETE("/dev/mail", devMailH.Clear)
+		}
+	}
+
+	// Prometheus 指标，配置了 METRICS_ADDR 时使用单独的端口
+	metrics.RegisterRedis(rdb)
+	if sqlDB, err := db.DB(); err == nil {
+		metrics.RegisterDB(sqlDB)
+	}
+	metricsHandler := metrics.Handler(cfg.Metrics.Token)
+	var metricsSrv *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler)
+		metricsSrv = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server stopped", "error", err)
+			}
+		}()
+	} else {
+		if cfg.Metrics.Token == "" && cfg.Server.Mode == gin.ReleaseMode {
+			slog.Warn("/metrics is public, set METRICS_TOKEN or METRICS_ADDR")
+		}
+		r.GET("/metrics", gin.WrapH(metricsHandler))
+	}
+
 	// Swagger
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// 用户认证
 	auth := r.Group("/auth")
 	{
+		auth.GET("/challenge", userH.Challenge)
 		auth.POST("/send-code", userH.SendCode)
+		auth.GET("/registration", userH.RegistrationMode)
 		auth.POST("/register", userH.Register)
 		auth.POST("/login", userH.Login)
 		auth.POST("/reset-password", userH.ResetPassword)
+
+		auth.GET("/oidc/providers", oidcH.Providers)
+		auth.GET("/oidc/:provider/login", oidcH.Login)
+		auth.GET("/oidc/:provider/callback", oidcH.Callback)
 	}
 
-	// 需要JWT认证的路由
+	// 需要JWT认证的路由，模拟登录 token 不能访问 noImp 标记的路由。
+	// API Key、计费、通知和成员相关的写操作都要加 noImp，否则可写的模拟 token 可以花费用户余额或转走安全通知
+	noImp := jwtMw.NoImpersonation()
 	userGroup := r.Group("/user", jwtMw.Auth())
 	{
-		userGroup.GET("/profile", func(c *gin.Context) {
-			userID := c.GetUint("user_id")
-			u, _ := userSvc.GetProfile(userID)
-			c.JSON(200, gin.H{"code": 0, "data": u})
-		})
-		userGroup.POST("/apikey", apiKeyH.Create)
+		userGroup.GET("/profile", userH.Profile)
+		userGroup.POST("/apikey", noImp, apiKeyH.Create)
 		userGroup.GET("/apikeys", apiKeyH.List)
-		userGroup.DELETE("/apikey/:id", apiKeyH.Delete)
+		userGroup.DELETE("/apikey/:id", noImp, apiKeyH.Delete)
+		userGroup.POST("/apikey/:id/rotate", noImp, apiKeyH.Rotate)
+		userGroup.PUT("/password", noImp, userH.ChangePassword)
+		userGroup.PUT("/locale", userH.SetLocale)
+		userGroup.GET("/audit", auditH.ListMine)
+		userGroup.GET("/notifications", notificationH.List)
+		userGroup.PUT("/notifications", noImp, notificationH.Set)
+		userGroup.DELETE("/notifications/:id", noImp, notificationH.Delete)
+		userGroup.POST("/notifications/:id/test", noImp, rateLimiter.PerUser("notify_test", 5, time.Hour), notificationH.Test)
+		userGroup.POST("/email/send-code", noImp, rateLimiter.PerUser("email_code", 5, time.Hour), userH.SendChangeEmailCode)
+		userGroup.PUT("/email", noImp, userH.ChangeEmail)
+		userGroup.POST("/invitations/accept", noImp, orgH.AcceptInvitation)
+		userGroup.GET("/identities", oidcH.Identities)
+		userGroup.POST("/identities/:provider/link", noImp, oidcH.Link)
+		userGroup.DELETE("/identities/:provider", noImp, oidcH.Unlink)
+		userGroup.GET("/export", noImp, userH.Export)
+		userGroup.POST("/delete/send-code", noImp, userH.SendDeleteCode)
+		userGroup.POST("/delete", noImp, userH.DeleteAccount)
+	}
+
+	// 组织
+	orgGroup := r.Group("/orgs", jwtMw.Auth())
+	{
+		orgGroup.POST("", noImp, orgH.Create)
+		orgGroup.GET("", orgH.List)
+		orgGroup.GET("/:id", orgH.Get)
+		orgGroup.GET("/:id/members", orgH.Members)
+		orgGroup.PUT("/:id/members/:user_id", noImp, orgH.UpdateMember)
+		orgGroup.DELETE("/:id/members/:user_id", noImp, orgH.RemoveMember)
+		orgGroup.POST("/:id/invitations", noImp, orgH.Invite)
+		orgGroup.POST("/:id/apikeys", noImp, orgH.CreateAPIKey)
+		orgGroup.GET("/:id/apikeys", orgH.ListAPIKeys)
+		orgGroup.DELETE("/:id/apikeys/:key_id", noImp, orgH.DeleteAPIKey)
+		orgGroup.POST("/:id/apikeys/:key_id/rotate", noImp, orgH.RotateAPIKey)
+		orgGroup.GET("/:id/usage", noImp, orgH.Usage)
+	}
+
+	// 管理员路由
+	admin := r.Group("/admin", jwtMw.Auth(), adminMw.RequireAdmin())
+	{
+		admin.GET("/endpoints", adminH.ListEndpoints)
+		admin.POST("/endpoints", adminH.CreateEndpoint)
+		admin.PUT("/endpoints/:id", adminH.UpdateEndpoint)
+		admin.PUT("/endpoints/:id/status", adminH.SetEndpointStatus)
+		admin.GET("/endpoints/stale", adminH.StaleEndpoints)
+		admin.GET("/audit", auditH.Search)
+		admin.GET("/health", healthH.Detail)
+		admin.GET("/settings", settingsH.List)
+		admin.PUT("/settings/:key", settingsH.Set)
+		admin.DELETE("/settings/:key", settingsH.Reset)
+		admin.POST("/users/:id/impersonate", adminH.Impersonate)
+		admin.GET("/invite-codes", adminH.ListInviteCodes)
+		admin.POST("/invite-codes", adminH.CreateInviteCode)
+		admin.DELETE("/invite-codes/:id", adminH.RevokeInviteCode)
+		admin.PUT("/orgs/:id/plan", adminH.SetOrgPlan)
 	}
 
 	// 公共API
 	var api *gin.RouterGroup
 	if rdb != nil {
-		rateLimiter := middleware.NewRateLimiter(rdb, 100, time.Minute)
-		api = r.Group("/api", rateLimiter.Limit())
+		api = r.Group("/api", rateLimiter.Limit(), endpointMw.Online())
 	} else {
-		api = r.Group("/api")
+		api = r.Group("/api", endpointMw.Online())
 	}
-	api.GET("/ip", coreH.IPQuery)
-	api.GET("/qq/avatar", contentH.QQAvatar)
+
+	reg := &registry{}
+	reg.GET(api, "/ip", endpoint.Route{Name: "IP地址查询", IsPublic: true, Tags: []string{"核心服务"}}, coreH.IPQuery)
+	reg.GET(api, "/qq/avatar", endpoint.Route{Name: "QQ头像获取", IsPublic: true, Tags: []string{"内容数据"}}, contentH.QQAvatar)
 
 	// 需要API Key的路由
-	apiAuth := api.Group("", apiKeyMw.Auth())
+	apiAuth := api.Group("", apiKeyMw.Auth(), billingMw.Charge())
 	{
-		apiAuth.POST("/crypto/encrypt", coreH.AESEncrypt)
-		apiAuth.POST("/crypto/decrypt", coreH.AESDecrypt)
-		apiAuth.GET("/bilibili/video", contentH.BilibiliVideo)
-		apiAuth.GET("/bilibili/video/url", contentH.BilibiliVideoURL)
-		apiAuth.GET("/douyin/video", coreH.DouyinVideo)
+		reg.POST(apiAuth, "/crypto/encrypt", endpoint.Route{Name: "AES加密", Cost: 1, Tags: []string{"核心服务"}}, coreH.AESEncrypt)
+		reg.POST(apiAuth, "/crypto/decrypt", endpoint.Route{Name: "AES解密", Cost: 1, Tags: []string{"核心服务"}}, coreH.AESDecrypt)
+		reg.GET(apiAuth, "/bilibili/video", endpoint.Route{Name: "B站视频信息", Cost: 1, Tags: []string{"内容数据"}}, contentH.BilibiliVideo)
+		reg.GET(apiAuth, "/bilibili/video/url", endpoint.Route{Name: "B站视频下载地址", Cost: 2, Tags: []string{"内容数据"}}, contentH.BilibiliVideoURL)
+		reg.GET(apiAuth, "/douyin/video", endpoint.Route{Name: "解析抖音视频", Cost: 2, Tags: []string{"核心服务"}}, coreH.DouyinVideo)
 	}
 
-	return r
+	// 同步路由到 APIConfig，已有配置不覆盖
+	stale, err := endpointSvc.Sync(context.Background(), reg.routes)
+	if err != nil {
+		slog.Warn("failed to sync api config", "error", err)
+	}
+	for _, cfg := range stale {
+		slog.Warn("api config has no registered route", "endpoint", cfg.Endpoint)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, run := range []func(context.Context){
+		endpointSvc.Watch,
+		settingsSvc.Watch,
+		mailQueue.Run,
+		func(ctx context.Context) {
+			userSvc.RunPurge(ctx, time.Duration(cfg.Account.RetentionDays)*24*time.Hour)
+		},
+		userSvc.RunKeyExpiry,
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(ctx)
+		}()
+	}
+
+	stop := func(ctx context.Context) error {
+		var errs []error
+		if metricsSrv != nil {
+			errs = append(errs, metricsSrv.Shutdown(ctx))
+		}
+		// 先等通知写入邮件队列，再停止消费队列
+		errs = append(errs, notifySvc.Wait(ctx))
+		cancel()
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			errs = append(errs, mailQueue.Close(ctx))
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+		}
+		return errors.Join(errs...)
+	}
+	return r, stop
 }
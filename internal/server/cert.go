@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// 最多每隔这么久检查一次证书文件是否更新
+const certCheckInterval = 30 * time.Second
+
+// certReloader 在握手时提供证书，证书或私钥文件的修改时间变化后重新加载，
+// 续期证书不需要重启服务。加载失败时继续使用旧证书
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= certCheckInterval {
+		if err := r.reload(); err != nil {
+			slog.Error("reload tls certificate failed, keep the current one", "error", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// reload 在文件比当前证书新时重新加载，调用方持有 mu
+func (r *certReloader) reload() error {
+	r.checked = time.Now()
+
+	var modTime time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil {
+		slog.Info("reloaded tls certificate", "file", r.certFile)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
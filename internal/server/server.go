@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// 两者都设置时使用 HTTPS
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// Server 是带超时设置和优雅停止的 HTTP 服务
+type Server struct {
+	srv *http.Server
+	// 取消所有请求的 context，停止超时后用于中断还在调用第三方服务的请求
+	cancel context.CancelFunc
+	tls    bool
+}
+
+func New(cfg Config, handler http.Handler) (*Server, error) {
+	base, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			BaseContext:       func(net.Listener) context.Context { return base },
+		},
+		cancel: cancel,
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			cancel()
+			return nil, errors.New("both TLS_CERT_FILE and TLS_KEY_FILE are required for TLS")
+		}
+		certs, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		s.srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+		s.tls = true
+	}
+	return s, nil
+}
+
+// TLS 返回是否使用 HTTPS
+func (s *Server) TLS() bool {
+	return s.tls
+}
+
+// ListenAndServe 阻塞直到服务停止，Shutdown 引起的停止返回 nil
+func (s *Server) ListenAndServe() error {
+	var err error
+	if s.tls {
+		// 证书由 TLSConfig.GetCertificate 提供
+		err = s.srv.ListenAndServeTLS("", "")
+	} else {
+		err = s.srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown 停止接受新连接并等待进行中的请求完成。ctx 结束时仍未完成的请求
+// 的 context 会被取消，连接被强制关闭
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	s.cancel()
+	if err != nil {
+		s.srv.Close()
+	}
+	return err
+}
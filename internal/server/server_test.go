@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startServer 在随机端口上启动 s，返回地址
+func startServer(t *testing.T, s *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.srv.Serve(ln)
+	return "http://" + ln.Addr().String()
+}
+
+func TestShutdownWaitsForRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s, err := New(Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startServer(t, s)
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				err = errors.New(resp.Status)
+			}
+		}
+		result <- err
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() { done <- s.Shutdown(context.Background()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown returned %v while a request was in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("in-flight request: %v", err)
+	}
+}
+
+func TestShutdownCancelsSlowRequests(t *testing.T) {
+	started, canceled := make(chan struct{}), make(chan struct{})
+	s, err := New(Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startServer(t, s)
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown: %v, want deadline exceeded", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("request context was not canceled after the shutdown timeout")
+	}
+}
+
+func TestNewRequiresBothTLSFiles(t *testing.T) {
+	if _, err := New(Config{TLSCertFile: "cert.pem"}, http.NotFoundHandler()); err == nil {
+		t.Error("cert without key accepted")
+	}
+	if _, err := New(Config{TLSKeyFile: "key.pem"}, http.NotFoundHandler()); err == nil {
+		t.Error("key without cert accepted")
+	}
+}
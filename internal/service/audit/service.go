@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"vapiv/internal/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ActionLoginSuccess   = "login.success"
+	ActionLoginFailure   = "login.failure"
+	ActionAPIKeyCreate   = "apikey.create"
+	ActionAPIKeyDelete   = "apikey.delete"
+	ActionAPIKeyRotate   = "apikey.rotate"
+	ActionPasswordChange = "password.change"
+	ActionPasswordReset  = "password.reset"
+	ActionEmailChange    = "email.change"
+	ActionAccountDelete  = "account.delete"
+	ActionIdentityLink   = "identity.link"
+	ActionIdentityUnlink = "identity.unlink"
+	ActionAdminEndpoint  = "admin.endpoint"
+	ActionImpersonate    = "admin.impersonate"
+	ActionInviteCreate   = "admin.invite.create"
+	ActionInviteRevoke   = "admin.invite.revoke"
+	ActionRegister       = "user.register"
+	ActionSettingUpdate  = "admin.setting.update"
+	ActionSettingReset   = "admin.setting.reset"
+	ActionOrgPlan        = "admin.org.plan"
+	// 模拟登录期间的每个请求
+	ActionImpersonatedRequest = "impersonation.request"
+)
+
+// Meta 描述发起操作的人和请求来源
+type Meta struct {
+	ActorID   uint
+	IP        string
+	UserAgent string
+}
+
+type Filter struct {
+	UserID  uint
+	ActorID uint
+	Action  string
+	From    time.Time
+	To      time.Time
+	Page    int
+	Size    int
+}
+
+// Service 只提供写入和查询，不提供修改或删除
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Record 写入审计事件，before/after 会序列化为 JSON；写入失败只记录日志，不影响业务
+func (s *Service) Record(ctx context.Context, meta Meta, userID uint, action string, before, after interface{}) {
+	event := &model.AuditEvent{
+		ActorID:   meta.ActorID,
+		UserID:    userID,
+		Action:    action,
+		IP:        meta.IP,
+		UserAgent: truncate(meta.UserAgent, 255),
+		Before:    marshal(before),
+		After:     marshal(after),
+	}
+	if err := s.db.WithContext(ctx).Create(event).Error; err != nil {
+		slog.Error("write audit event failed", "action", action, "user_id", userID, "error", err)
+	}
+}
+
+func (s *Service) ListForUser(ctx context.Context, userID uint, page, size int) ([]model.AuditEvent, int64, error) {
+	return s.Search(ctx, Filter{UserID: userID, Page: page, Size: size})
+}
+
+func (s *Service) Search(ctx context.Context, f Filter) ([]model.AuditEvent, int64, error) {
+	q := s.db.WithContext(ctx).Model(&model.AuditEvent{})
+	if f.UserID != 0 {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	if f.ActorID != 0 {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if f.Size <= 0 || f.Size > 100 {
+		f.Size = 20
+	}
+	if f.Page <= 0 {
+		f.Page = 1
+	}
+
+	var events []model.AuditEvent
+	err := q.Order("id DESC").Offset((f.Page - 1) * f.Size).Limit(f.Size).Find(&events).Error
+	return events, total, err
+}
+
+func marshal(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -1,15 +1,28 @@
 package content
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"vapiv/internal/service/settings"
+	"vapiv/internal/service/upstream"
 )
 
-type BilibiliService struct{}
+const biliUpstream = "bilibili"
+
+type BilibiliService struct {
+	settings *settings.Service
+}
 
-func NewBilibiliService() *BilibiliService {
-	return &BilibiliService{}
+func NewBilibiliService(settings *settings.Service) *BilibiliService {
+	return &BilibiliService{settings: settings}
+}
+
+// client 每次请求时按 bilibili.timeout 的当前值创建
+func (s *BilibiliService) client() *http.Client {
+	return &http.Client{Timeout: s.settings.Duration(settings.BilibiliTimeout)}
 }
 
 type VideoInfo struct {
@@ -33,24 +46,19 @@ type VideoURL struct {
 	Duration    int64    `json:"duration"`
 }
 
-func (s *BilibiliService) GetVideoInfo(bvid string) (*VideoInfo, error) {
+func (s *BilibiliService) GetVideoInfo(ctx context.Context, bvid string) (*VideoInfo, error) {
 	url := fmt.Sprintf("https://api.bilibili.com/x/web-interface/view?bvid=%s", bvid)
-	resp, err := http.Get(url)
+	resp, err := upstream.Get(ctx, s.client(), biliUpstream, "view", url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	data, err := biliData("view", resp)
+	if err != nil {
 		return nil, err
 	}
 
-	data, ok := result["data"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response")
-	}
-
 	stat, _ := data["stat"].(map[string]interface{})
 	owner, _ := data["owner"].(map[string]interface{})
 
@@ -68,39 +76,37 @@ func (s *BilibiliService) GetVideoInfo(bvid string) (*VideoInfo, error) {
 	}, nil
 }
 
-func (s *BilibiliService) GetVideoURL(bvid string) (*VideoURL, error) {
-	info, err := s.GetVideoInfo(bvid)
+func (s *BilibiliService) GetVideoURL(ctx context.Context, bvid string) (*VideoURL, error) {
+	info, err := s.GetVideoInfo(ctx, bvid)
 	if err != nil {
 		return nil, err
 	}
 
 	url := fmt.Sprintf("https://api.bilibili.com/x/player/playurl?bvid=%s&cid=%d&qn=80&fnval=1", bvid, info.Cid)
-	req, _ := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Set("Referer", "https://www.bilibili.com")
 	req.Header.Set("User-Agent", "Mozilla/5.0")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := upstream.Do(s.client(), biliUpstream, "playurl", req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	data, err := biliData("playurl", resp)
+	if err != nil {
 		return nil, err
 	}
 
-	data, ok := result["data"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response")
-	}
-
 	durl, ok := data["durl"].([]interface{})
 	if !ok || len(durl) == 0 {
-		return nil, fmt.Errorf("no video url found")
+		return nil, upstream.Wrap(biliUpstream, "playurl", upstream.ErrNotFound, fmt.Errorf("no video url found"))
 	}
 
-	first := durl[0].(map[string]interface{})
+	first, ok := durl[0].(map[string]interface{})
+	if !ok {
+		return nil, upstream.Wrap(biliUpstream, "playurl", upstream.ErrParse, fmt.Errorf("unexpected durl"))
+	}
 	return &VideoURL{
 		Quality:     int(getInt64(data, "quality")),
 		Description: info.Title,
@@ -108,3 +114,35 @@ func (s *BilibiliService) GetVideoURL(bvid string) (*VideoURL, error) {
 		Duration:    getInt64(first, "length") / 1000,
 	}, nil
 }
+
+// biliData 解析 B站接口的通用响应，按 code 区分参数错误、不存在和风控
+func biliData(op string, resp *http.Response) (map[string]interface{}, error) {
+	if err := upstream.CheckStatus(biliUpstream, op, resp); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code    int                    `json:"code"`
+		Message string                 `json:"message"`
+		Data    map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, upstream.Wrap(biliUpstream, op, upstream.ErrParse, err)
+	}
+
+	switch result.Code {
+	case 0:
+	case -400:
+		return nil, upstream.Wrap(biliUpstream, op, upstream.ErrInvalidInput, fmt.Errorf("%d %s", result.Code, result.Message))
+	case -404, 62002, 62004:
+		return nil, upstream.Wrap(biliUpstream, op, upstream.ErrNotFound, fmt.Errorf("%d %s", result.Code, result.Message))
+	case -403, -412, -352:
+		return nil, upstream.Wrap(biliUpstream, op, upstream.ErrBlocked, fmt.Errorf("%d %s", result.Code, result.Message))
+	default:
+		return nil, upstream.Wrap(biliUpstream, op, upstream.ErrUnavailable, fmt.Errorf("%d %s", result.Code, result.Message))
+	}
+	if result.Data == nil {
+		return nil, upstream.Wrap(biliUpstream, op, upstream.ErrParse, fmt.Errorf("missing data"))
+	}
+	return result.Data, nil
+}
@@ -6,9 +6,13 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 )
 
+// ErrInvalidCiphertext 密文不是合法的 base64 或长度不足
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
 type CryptoService struct{}
 
 func NewCryptoService() *CryptoService {
@@ -39,7 +43,7 @@ func (s *CryptoService) AESDecrypt(ciphertext, key string) (string, error) {
 	keyBytes := padKey([]byte(key))
 	cipherBytes, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
 	}
 
 	block, err := aes.NewCipher(keyBytes)
@@ -48,7 +52,7 @@ func (s *CryptoService) AESDecrypt(ciphertext, key string) (string, error) {
 	}
 
 	if len(cipherBytes) < aes.BlockSize {
-		return "", errors.New("ciphertext too short")
+		return "", fmt.Errorf("%w: too short", ErrInvalidCiphertext)
 	}
 
 	iv := cipherBytes[:aes.BlockSize]
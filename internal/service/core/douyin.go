@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,22 +9,24 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
+
+	"vapiv/internal/service/settings"
+	"vapiv/internal/service/upstream"
 )
 
+const douyinUpstream = "douyin"
+
 type DouyinService struct {
-	client *http.Client
+	settings *settings.Service
 }
 
-func NewDouyinService() *DouyinService {
-	return &DouyinService{
-		client: &http.Client{
-			Timeout: 15 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-		},
-	}
+func NewDouyinService(settings *settings.Service) *DouyinService {
+	return &DouyinService{settings: settings}
+}
+
+// client 每次请求时按 douyin.timeout 的当前值创建，连接池由默认 Transport 共享
+func (s *DouyinService) client() *http.Client {
+	return &http.Client{Timeout: s.settings.Duration(settings.DouyinTimeout)}
 }
 
 type DouyinVideo struct {
@@ -45,16 +48,16 @@ type DouyinVideo struct {
 	Tags         []string `json:"tags"`
 }
 
-func (s *DouyinService) ParseVideo(shareURL string) (*DouyinVideo, error) {
-	videoID, err := s.extractVideoID(shareURL)
+func (s *DouyinService) ParseVideo(ctx context.Context, shareURL string) (*DouyinVideo, error) {
+	videoID, err := s.extractVideoID(ctx, shareURL)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.getVideoInfo(videoID)
+	return s.getVideoInfo(ctx, videoID)
 }
 
-func (s *DouyinService) extractVideoID(shareURL string) (string, error) {
+func (s *DouyinService) extractVideoID(ctx context.Context, shareURL string) (string, error) {
 	// 如果直接是视频ID（纯数字）
 	if matched, _ := regexp.MatchString(`^\d+$`, shareURL); matched {
 		return shareURL, nil
@@ -62,7 +65,7 @@ func (s *DouyinService) extractVideoID(shareURL string) (string, error) {
 
 	// 处理短链接，获取重定向后的真实URL
 	if strings.Contains(shareURL, "v.douyin.com") || strings.Contains(shareURL, "vm.tiktok.com") {
-		realURL, err := s.getRealURL(shareURL)
+		realURL, err := s.getRealURL(ctx, shareURL)
 		if err != nil {
 			return "", err
 		}
@@ -84,18 +87,22 @@ func (s *DouyinService) extractVideoID(shareURL string) (string, error) {
 		}
 	}
 
-	return "", errors.New("无法从URL中提取视频ID")
+	return "", upstream.Wrap(douyinUpstream, "extract video id", upstream.ErrInvalidInput, nil)
 }
 
-func (s *DouyinService) getRealURL(shortURL string) (string, error) {
-	req, err := http.NewRequest("GET", shortURL, nil)
+func (s *DouyinService) getRealURL(ctx context.Context, shortURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", shortURL, nil)
 	if err != nil {
-		return "", err
+		return "", upstream.Wrap(douyinUpstream, "resolve short link", upstream.ErrInvalidInput, err)
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1")
 
-	resp, err := s.client.Do(req)
+	client := s.client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := upstream.Do(client, douyinUpstream, "resolve short link", req)
 	if err != nil {
 		return "", err
 	}
@@ -103,17 +110,21 @@ func (s *DouyinService) getRealURL(shortURL string) (string, error) {
 
 	location := resp.Header.Get("Location")
 	if location == "" {
-		return "", errors.New("无法获取重定向URL")
+		if err := upstream.CheckStatus(douyinUpstream, "resolve short link", resp); err != nil {
+			return "", err
+		}
+		// 短链接不存在时返回 200 页面而不是跳转
+		return "", upstream.Wrap(douyinUpstream, "resolve short link", upstream.ErrNotFound, nil)
 	}
 
 	return location, nil
 }
 
-func (s *DouyinService) getVideoInfo(videoID string) (*DouyinVideo, error) {
+func (s *DouyinService) getVideoInfo(ctx context.Context, videoID string) (*DouyinVideo, error) {
 	// 直接访问抖音网页版获取SSR数据
 	pageURL := fmt.Sprintf("https://www.douyin.com/video/%s", videoID)
 
-	req, err := http.NewRequest("GET", pageURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -123,16 +134,18 @@ func (s *DouyinService) getVideoInfo(videoID string) (*DouyinVideo, error) {
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.Header.Set("Cookie", "ttwid=1%7C1234567890; __ac_nonce=0")
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstream.Do(s.client(), douyinUpstream, "fetch page", req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if err := upstream.CheckStatus(douyinUpstream, "fetch page", resp); err != nil {
+		return nil, err
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, upstream.Wrap(douyinUpstream, "fetch page", upstream.ErrUnavailable, err)
 	}
 
 	htmlContent := string(body)
@@ -148,7 +161,7 @@ func (s *DouyinService) getVideoInfo(videoID string) (*DouyinVideo, error) {
 	}
 
 	if len(matches) < 2 {
-		return nil, errors.New("无法从页面中提取视频数据，请检查链接是否有效")
+		return nil, upstream.Wrap(douyinUpstream, "fetch page", upstream.ErrParse, errors.New("render data not found"))
 	}
 
 	// URL解码
@@ -166,7 +179,7 @@ func (s *DouyinService) getVideoInfo(videoID string) (*DouyinVideo, error) {
 	video, err := s.parseRenderData(jsonData, videoID)
 	if err != nil {
 		// 尝试备用API
-		return s.getVideoInfoFromAPI(videoID)
+		return s.getVideoInfoFromAPI(ctx, videoID)
 	}
 
 	return video, nil
@@ -181,7 +194,7 @@ func (s *DouyinService) parseRenderData(jsonData string, videoID string) (*Douyi
 	// 遍历查找aweme_detail
 	awemeDetail := s.findAwemeDetail(data)
 	if awemeDetail == nil {
-		return nil, errors.New("未找到视频详情")
+		return nil, upstream.Wrap(douyinUpstream, "parse render data", upstream.ErrParse, errors.New("aweme detail not found"))
 	}
 
 	video := &DouyinVideo{
@@ -259,31 +272,34 @@ func (s *DouyinService) findAwemeDetail(data interface{}) map[string]interface{}
 	return nil
 }
 
-func (s *DouyinService) getVideoInfoFromAPI(videoID string) (*DouyinVideo, error) {
+func (s *DouyinService) getVideoInfoFromAPI(ctx context.Context, videoID string) (*DouyinVideo, error) {
 	// 备用API
 	apiURL := fmt.Sprintf("https://www.iesdouyin.com/web/api/v2/aweme/iteminfo/?item_ids=%s", videoID)
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15")
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstream.Do(s.client(), douyinUpstream, "item info", req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if err := upstream.CheckStatus(douyinUpstream, "item info", resp); err != nil {
+		return nil, err
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, upstream.Wrap(douyinUpstream, "item info", upstream.ErrUnavailable, err)
 	}
 
+	// 被风控时返回空 body
 	if len(body) == 0 {
-		return nil, errors.New("无法获取视频信息，可能是网络限制或视频不存在")
+		return nil, upstream.Wrap(douyinUpstream, "item info", upstream.ErrBlocked, errors.New("empty response"))
 	}
 
 	var result struct {
@@ -323,11 +339,11 @@ func (s *DouyinService) getVideoInfoFromAPI(videoID string) (*DouyinVideo, error
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+		return nil, upstream.Wrap(douyinUpstream, "item info", upstream.ErrParse, err)
 	}
 
 	if len(result.ItemList) == 0 {
-		return nil, errors.New("未找到视频信息，可能视频已删除或链接无效")
+		return nil, upstream.Wrap(douyinUpstream, "item info", upstream.ErrNotFound, nil)
 	}
 
 	item := result.ItemList[0]
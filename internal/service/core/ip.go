@@ -1,15 +1,27 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+
+	"vapiv/internal/service/settings"
+	"vapiv/internal/service/upstream"
 )
 
-type IPService struct{}
+type IPService struct {
+	settings *settings.Service
+}
+
+func NewIPService(settings *settings.Service) *IPService {
+	return &IPService{settings: settings}
+}
 
-func NewIPService() *IPService {
-	return &IPService{}
+// client 每次请求时按 ip.timeout 的当前值创建
+func (s *IPService) client() *http.Client {
+	return &http.Client{Timeout: s.settings.Duration(settings.IPTimeout)}
 }
 
 type IPInfo struct {
@@ -20,27 +32,29 @@ type IPInfo struct {
 	ISP      string `json:"isp"`
 }
 
-func (s *IPService) Query(ip string) (*IPInfo, error) {
+func (s *IPService) Query(ctx context.Context, ip string) (*IPInfo, error) {
 	// 如果是内网IP或空，获取公网IP
 	if ip == "" || ip == "127.0.0.1" || ip == "::1" || isPrivateIP(ip) {
 		ip = ""
 	}
 
-	resp, err := http.Get(fmt.Sprintf("http://ip-api.com/json/%s?lang=zh-CN", ip))
+	resp, err := upstream.Get(ctx, s.client(), "ip-api", "query", fmt.Sprintf("http://ip-api.com/json/%s?lang=zh-CN", ip))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if err := upstream.CheckStatus("ip-api", "query", resp); err != nil {
+		return nil, err
+	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, upstream.Wrap("ip-api", "query", upstream.ErrParse, err)
 	}
 
-	// 检查API返回状态
+	// 检查API返回状态，失败原因如 invalid query、reserved range
 	if status, ok := result["status"].(string); ok && status == "fail" {
-		msg := getString(result, "message")
-		return &IPInfo{IP: ip, Country: "查询失败: " + msg}, nil
+		return nil, upstream.Wrap("ip-api", "query", upstream.ErrInvalidInput, errors.New(getString(result, "message")))
 	}
 
 	return &IPInfo{
@@ -0,0 +1,196 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Param 是从 swagger 文档中提取的参数说明
+type Param struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// Entry 是公开目录中的一项
+type Entry struct {
+	Endpoint    string   `json:"endpoint"`
+	Method      string   `json:"method"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Cost        int64    `json:"cost"`
+	Auth        string   `json:"auth"`
+	RateTier    string   `json:"rate_tier"`
+	Tags        []string `json:"tags"`
+	Params      []Param  `json:"params"`
+}
+
+type operation struct {
+	description string
+	params      []Param
+}
+
+// Catalog 把 APIConfig 和 swagger 注释中的参数说明合并成对外的接口目录
+type Catalog struct {
+	svc        *Service
+	operations map[string]operation
+}
+
+func NewCatalog(svc *Service, swaggerDoc string) (*Catalog, error) {
+	ops, err := parseSwagger(swaggerDoc)
+	if err != nil {
+		return nil, err
+	}
+	return &Catalog{svc: svc, operations: ops}, nil
+}
+
+// Entries 返回所有已上线且仍有路由的接口
+func (c *Catalog) Entries() []Entry {
+	c.svc.mu.RLock()
+	defer c.svc.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(c.svc.configs))
+	for _, cfg := range c.svc.configs {
+		if cfg.Status != StatusOnline {
+			continue
+		}
+		if c.svc.routes != nil && !c.svc.routes[cfg.Endpoint] {
+			continue
+		}
+
+		entry := Entry{
+			Endpoint: cfg.Endpoint,
+			Method:   cfg.Method,
+			Name:     cfg.Name,
+			Cost:     cfg.Cost,
+			Auth:     "none",
+			RateTier: cfg.RateTier,
+			Tags:     []string{},
+			Params:   []Param{},
+		}
+		if !cfg.IsPublic {
+			entry.Auth = "api_key"
+		}
+		if cfg.Tags != "" {
+			entry.Tags = strings.Split(cfg.Tags, ",")
+		}
+		if op, ok := c.operations[operationKey(cfg.Method, cfg.Endpoint)]; ok {
+			entry.Description = op.description
+			entry.Params = op.params
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Endpoint < entries[j].Endpoint
+	})
+	return entries
+}
+
+type swaggerDoc struct {
+	Paths       map[string]map[string]swaggerOperation `json:"paths"`
+	Definitions map[string]swaggerSchema               `json:"definitions"`
+}
+
+type swaggerOperation struct {
+	Summary     string             `json:"summary"`
+	Description string             `json:"description"`
+	Parameters  []swaggerParameter `json:"parameters"`
+}
+
+type swaggerParameter struct {
+	Name        string        `json:"name"`
+	In          string        `json:"in"`
+	Type        string        `json:"type"`
+	Required    bool          `json:"required"`
+	Description string        `json:"description"`
+	Schema      swaggerSchema `json:"schema"`
+}
+
+type swaggerSchema struct {
+	Ref         string                   `json:"$ref"`
+	Type        string                   `json:"type"`
+	Description string                   `json:"description"`
+	Required    []string                 `json:"required"`
+	Properties  map[string]swaggerSchema `json:"properties"`
+}
+
+func parseSwagger(doc string) (map[string]operation, error) {
+	var sw swaggerDoc
+	if err := json.Unmarshal([]byte(doc), &sw); err != nil {
+		return nil, err
+	}
+
+	ops := map[string]operation{}
+	for path, methods := range sw.Paths {
+		for method, op := range methods {
+			desc := op.Description
+			if desc == "" {
+				desc = op.Summary
+			}
+
+			params := make([]Param, 0, len(op.Parameters))
+			for _, p := range op.Parameters {
+				if p.In != "body" {
+					params = append(params, Param{
+						Name:        p.Name,
+						In:          p.In,
+						Type:        p.Type,
+						Required:    p.Required,
+						Description: p.Description,
+					})
+					continue
+				}
+				params = append(params, bodyParams(sw.Definitions, p.Schema)...)
+			}
+
+			ops[operationKey(method, swaggerToGinPath(path))] = operation{description: desc, params: params}
+		}
+	}
+	return ops, nil
+}
+
+// bodyParams 将请求体的 schema 展开为字段列表
+func bodyParams(defs map[string]swaggerSchema, schema swaggerSchema) []Param {
+	if schema.Ref != "" {
+		schema = defs[strings.TrimPrefix(schema.Ref, "#/definitions/")]
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	params := make([]Param, 0, len(schema.Properties))
+	for name, prop := range schema.Properties {
+		params = append(params, Param{
+			Name:        name,
+			In:          "body",
+			Type:        prop.Type,
+			Required:    required[name],
+			Description: prop.Description,
+		})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
+
+func operationKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// swaggerToGinPath 把 /a/{id} 转换成 gin 的 /a/:id
+func swaggerToGinPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			parts[i] = ":" + p[1:len(p)-1]
+		}
+	}
+	return strings.Join(parts, "/")
+}
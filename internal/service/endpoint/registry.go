@@ -0,0 +1,106 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"vapiv/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// 限流等级只在接口目录中展示给调用方，修改时需要同步 handler.UpdateEndpointReq 的校验
+const (
+	RateTierLow      = "low"
+	RateTierStandard = "standard"
+	RateTierHigh     = "high"
+
+	DefaultRateTier = RateTierStandard
+)
+
+// Route 描述一个在路由中注册的 API 及其默认计费配置
+type Route struct {
+	Method   string
+	Path     string
+	Name     string
+	Cost     int64
+	IsPublic bool
+	RateTier string
+	Tags     []string
+}
+
+// Sync 为新路由插入默认配置，已有配置保持管理员的修改不变，
+// 返回数据库中已经没有对应路由的配置。单个路由写入失败不影响其他路由，
+// 无论是否出错都会重新加载缓存，否则计费和上下线检查会把所有接口当作没有配置
+func (s *Service) Sync(ctx context.Context, routes []Route) ([]model.APIConfig, error) {
+	known := make(map[string]bool, len(routes))
+	var errs []error
+	for _, r := range routes {
+		known[r.Path] = true
+		if err := s.syncRoute(ctx, r); err != nil {
+			errs = append(errs, fmt.Errorf("sync %s: %w", r.Path, err))
+		}
+	}
+
+	s.mu.Lock()
+	s.routes = known
+	s.mu.Unlock()
+
+	if err := s.Load(ctx); err != nil {
+		return nil, errors.Join(append(errs, err)...)
+	}
+	stale, err := s.Stale(ctx)
+	return stale, errors.Join(append(errs, err)...)
+}
+
+func (s *Service) syncRoute(ctx context.Context, r Route) error {
+	if r.RateTier == "" {
+		r.RateTier = DefaultRateTier
+	}
+	cfg := model.APIConfig{
+		Endpoint: r.Path,
+		Method:   r.Method,
+		Name:     r.Name,
+		Cost:     r.Cost,
+		IsPublic: r.IsPublic,
+		Status:   StatusOnline,
+		Tags:     strings.Join(r.Tags, ","),
+		RateTier: r.RateTier,
+	}
+	db := s.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Select("Endpoint", "Method", "Name", "Cost", "IsPublic", "Status", "Tags", "RateTier", "UpdatedAt").
+		Create(&cfg).Error
+	if err != nil {
+		return err
+	}
+
+	// 早期手工插入的记录没有 method/tags，只补空字段
+	err = db.Model(&model.APIConfig{}).Where("endpoint = ? AND (method IS NULL OR method = '')", r.Path).Update("method", r.Method).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&model.APIConfig{}).Where("endpoint = ? AND (tags IS NULL OR tags = '')", r.Path).Update("tags", cfg.Tags).Error
+}
+
+// Stale 返回没有对应路由的配置
+func (s *Service) Stale(ctx context.Context) ([]model.APIConfig, error) {
+	s.mu.RLock()
+	known := s.routes
+	s.mu.RUnlock()
+
+	var list []model.APIConfig
+	if err := s.db.WithContext(ctx).Order("endpoint").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	stale := make([]model.APIConfig, 0)
+	for _, cfg := range list {
+		if !known[cfg.Endpoint] {
+			stale = append(stale, cfg)
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,98 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vapiv/internal/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.APIConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db, nil)
+}
+
+func TestSyncKeepsExistingConfig(t *testing.T) {
+	s := newTestService(t)
+	existing := model.APIConfig{Endpoint: "/api/a", Name: "改过的名字", Cost: 5}
+	s.db.Create(&existing)
+	s.db.Model(&existing).Update("status", StatusOffline)
+
+	stale, err := s.Sync(context.Background(), []Route{
+		{Method: "GET", Path: "/api/a", Name: "A", Cost: 1, Tags: []string{"x"}},
+		{Method: "POST", Path: "/api/b", Name: "B", Cost: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("stale = %v", stale)
+	}
+
+	a, ok := s.Get("/api/a")
+	if !ok || a.Cost != 5 || a.Name != "改过的名字" || a.Status != StatusOffline {
+		t.Errorf("existing config overwritten: %+v", a)
+	}
+	if a.Method != "GET" || a.Tags != "x" {
+		t.Errorf("empty method/tags not backfilled: %+v", a)
+	}
+	if b, ok := s.Get("/api/b"); !ok || b.Cost != 2 || b.RateTier != DefaultRateTier || b.Status != StatusOnline {
+		t.Errorf("new config = %+v, %v", b, ok)
+	}
+}
+
+// 一个路由写入失败时仍然同步其他路由，并加载已有配置
+func TestSyncLoadsCacheAfterInsertError(t *testing.T) {
+	s := newTestService(t)
+	s.db.Create(&model.APIConfig{Endpoint: "/api/paid", Cost: 3, Status: StatusOnline})
+
+	errInsert := errors.New("insert failed")
+	s.db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		if cfg, ok := tx.Statement.Dest.(*model.APIConfig); ok && cfg.Endpoint == "/api/bad" {
+			tx.AddError(errInsert)
+		}
+	})
+
+	_, err := s.Sync(context.Background(), []Route{
+		{Method: "GET", Path: "/api/bad", Cost: 1},
+		{Method: "GET", Path: "/api/paid", Cost: 1},
+		{Method: "GET", Path: "/api/new", Cost: 1},
+	})
+	if !errors.Is(err, errInsert) {
+		t.Fatalf("err = %v, want insert error", err)
+	}
+	if cfg, ok := s.Get("/api/paid"); !ok || cfg.Cost != 3 {
+		t.Errorf("existing config not loaded: %+v, %v", cfg, ok)
+	}
+	if _, ok := s.Get("/api/new"); !ok {
+		t.Error("routes after the failed one were not synced")
+	}
+}
+
+func TestSyncReportsStale(t *testing.T) {
+	s := newTestService(t)
+	s.db.Create(&model.APIConfig{Endpoint: "/api/removed"})
+
+	stale, err := s.Sync(context.Background(), []Route{{Method: "GET", Path: "/api/a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 1 || stale[0].Endpoint != "/api/removed" {
+		t.Errorf("stale = %+v", stale)
+	}
+}
@@ -0,0 +1,159 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"vapiv/internal/model"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusOffline = 0
+	StatusOnline  = 1
+)
+
+// changedChannel 用于在多个实例之间广播 APIConfig 变更
+const changedChannel = "apiconfig:changed"
+
+var ErrNotFound = errors.New("endpoint config not found")
+
+// Service 管理 APIConfig，并在内存中缓存一份供中间件读取
+type Service struct {
+	db  *gorm.DB
+	rdb *redis.Client
+
+	mu      sync.RWMutex
+	configs map[string]model.APIConfig
+	routes  map[string]bool
+}
+
+func NewService(db *gorm.DB, rdb *redis.Client) *Service {
+	return &Service{db: db, rdb: rdb, configs: map[string]model.APIConfig{}}
+}
+
+// Load 从数据库重新加载全部配置
+func (s *Service) Load(ctx context.Context) error {
+	var list []model.APIConfig
+	if err := s.db.WithContext(ctx).Find(&list).Error; err != nil {
+		return err
+	}
+
+	configs := make(map[string]model.APIConfig, len(list))
+	for _, cfg := range list {
+		configs[cfg.Endpoint] = cfg
+	}
+
+	s.mu.Lock()
+	s.configs = configs
+	s.mu.Unlock()
+	return nil
+}
+
+// Get 按路由路径读取缓存中的配置
+func (s *Service) Get(endpoint string) (model.APIConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfg, ok := s.configs[endpoint]
+	return cfg, ok
+}
+
+// Watch 订阅变更通知，收到后重新加载；redis 不可用时仅本实例生效
+func (s *Service) Watch(ctx context.Context) {
+	if s.rdb == nil {
+		return
+	}
+
+	sub := s.rdb.Subscribe(ctx, changedChannel)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			if err := s.Load(ctx); err != nil {
+				slog.Error("reload api config failed", "error", err)
+			}
+		}
+	}
+}
+
+func (s *Service) List(ctx context.Context) ([]model.APIConfig, error) {
+	var list []model.APIConfig
+	err := s.db.WithContext(ctx).Order("endpoint").Find(&list).Error
+	return list, err
+}
+
+func (s *Service) Create(ctx context.Context, cfg *model.APIConfig) error {
+	// is_public/status 带有数据库默认值，零值需要显式写入；
+	// Create 会把默认值回填到 cfg，所以先保存调用方给的值
+	isPublic, status := cfg.IsPublic, cfg.Status
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(cfg).Error; err != nil {
+			return err
+		}
+		cfg.IsPublic, cfg.Status = isPublic, status
+		return tx.Model(cfg).Updates(map[string]interface{}{
+			"is_public": isPublic,
+			"status":    status,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.changed(ctx)
+}
+
+func (s *Service) Find(ctx context.Context, id uint) (*model.APIConfig, error) {
+	var cfg model.APIConfig
+	if err := s.db.WithContext(ctx).First(&cfg, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (s *Service) Update(ctx context.Context, id uint, fields map[string]interface{}) (*model.APIConfig, error) {
+	cfg, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.db.WithContext(ctx)
+	if len(fields) > 0 {
+		if err := db.Model(cfg).Updates(fields).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := db.First(cfg, id).Error; err != nil {
+		return nil, err
+	}
+	return cfg, s.changed(ctx)
+}
+
+func (s *Service) SetStatus(ctx context.Context, id uint, status int) (*model.APIConfig, error) {
+	return s.Update(ctx, id, map[string]interface{}{"status": status})
+}
+
+// changed 刷新本地缓存并通知其他实例
+func (s *Service) changed(ctx context.Context) error {
+	if err := s.Load(ctx); err != nil {
+		return err
+	}
+	if s.rdb != nil {
+		if err := s.rdb.Publish(ctx, changedChannel, "reload").Err(); err != nil {
+			slog.Error("publish api config change failed", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package endpoint
+
+import (
+	"context"
+	"testing"
+
+	"vapiv/internal/model"
+)
+
+func TestCreateKeepsZeroValues(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	cfg := &model.APIConfig{Endpoint: "/api/private", Method: "GET", Name: "private", IsPublic: false, Status: StatusOffline}
+	if err := s.Create(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IsPublic || cfg.Status != StatusOffline {
+		t.Errorf("returned config is_public=%v status=%d, want false and %d", cfg.IsPublic, cfg.Status, StatusOffline)
+	}
+
+	stored, err := s.Find(ctx, cfg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.IsPublic || stored.Status != StatusOffline || stored.Method != "GET" || stored.RateTier != DefaultRateTier {
+		t.Errorf("stored config %+v", stored)
+	}
+	if cached, ok := s.Get("/api/private"); !ok || cached.IsPublic {
+		t.Errorf("cached config %+v, %v", cached, ok)
+	}
+}
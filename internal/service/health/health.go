@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"vapiv/internal/service/upstream"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 整体状态和单项检查状态
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	// Redis 启动时不可用，服务在没有 Redis 的情况下运行
+	StatusDisabled = "disabled"
+)
+
+const checkTimeout = 2 * time.Second
+
+type Check struct {
+	Status string `json:"status"`
+	// 是否影响就绪状态，可选依赖不可用时整体为 degraded
+	Required  bool   `json:"required"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Redacted 去掉错误信息，用于不需要认证的 /readyz，错误中可能包含主机名和数据库用户
+func (r Report) Redacted() Report {
+	checks := make(map[string]Check, len(r.Checks))
+	for name, c := range r.Checks {
+		c.Error = ""
+		checks[name] = c
+	}
+	return Report{Status: r.Status, Checks: checks}
+}
+
+// Detail 是管理员查看的详细状态，包括根据最近调用估计的第三方服务可达性
+type Detail struct {
+	Report
+	Upstreams []upstream.Reachability `json:"upstreams"`
+}
+
+type Service struct {
+	db  *gorm.DB
+	rdb *redis.Client
+	// Redis 是否是必需依赖，为 false 时 Redis 不可用只会降级（关闭限流、邮件直接发送等）
+	redisRequired bool
+}
+
+func NewService(db *gorm.DB, rdb *redis.Client, redisRequired bool) *Service {
+	return &Service{db: db, rdb: rdb, redisRequired: redisRequired}
+}
+
+// Ready 检查数据库和 Redis。必需依赖不可用时为 down，只有可选依赖不可用时为 degraded
+func (s *Service) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		checks = map[string]Check{}
+	)
+	run := func(name string, required bool, ping func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := Check{Status: StatusOK, Required: required}
+			start := time.Now()
+			if err := ping(ctx); err != nil {
+				c.Status = StatusDown
+				c.Error = err.Error()
+			}
+			c.LatencyMs = time.Since(start).Milliseconds()
+			mu.Lock()
+			checks[name] = c
+			mu.Unlock()
+		}()
+	}
+
+	run("database", true, func(ctx context.Context) error {
+		sqlDB, err := s.db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	if s.rdb != nil {
+		run("redis", s.redisRequired, func(ctx context.Context) error {
+			return s.rdb.Ping(ctx).Err()
+		})
+	} else {
+		checks["redis"] = Check{Status: StatusDisabled, Required: s.redisRequired}
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: checks}
+	for _, c := range checks {
+		if c.Status == StatusOK {
+			continue
+		}
+		if c.Required {
+			report.Status = StatusDown
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+func (s *Service) Detail(ctx context.Context) Detail {
+	return Detail{Report: s.Ready(ctx), Upstreams: upstream.Stats()}
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T, closed bool) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	if closed {
+		sqlDB.Close()
+	} else {
+		t.Cleanup(func() { sqlDB.Close() })
+	}
+	return db
+}
+
+func newTestRedis(t *testing.T, closed bool) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	if closed {
+		mr.Close()
+	}
+	return rdb
+}
+
+func TestReady(t *testing.T) {
+	const (
+		up = iota
+		down
+		none
+	)
+	tests := []struct {
+		name          string
+		db            int
+		redis         int
+		redisRequired bool
+		status        string
+		redisStatus   string
+	}{
+		{"all up", up, up, false, StatusOK, StatusOK},
+		{"optional redis down", up, down, false, StatusDegraded, StatusDown},
+		{"optional redis disabled", up, none, false, StatusDegraded, StatusDisabled},
+		{"required redis down", up, down, true, StatusDown, StatusDown},
+		{"required redis disabled", up, none, true, StatusDown, StatusDisabled},
+		{"database down", down, up, false, StatusDown, StatusOK},
+		{"database and optional redis down", down, down, false, StatusDown, StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rdb *redis.Client
+			if tt.redis != none {
+				rdb = newTestRedis(t, tt.redis == down)
+			}
+			report := NewService(newTestDB(t, tt.db == down), rdb, tt.redisRequired).Ready(context.Background())
+
+			if report.Status != tt.status {
+				t.Errorf("status %q, want %q", report.Status, tt.status)
+			}
+			if c := report.Checks["redis"]; c.Status != tt.redisStatus || c.Required != tt.redisRequired {
+				t.Errorf("redis check %+v", c)
+			}
+			db := report.Checks["database"]
+			if !db.Required || (db.Status == StatusDown) != (tt.db == down) {
+				t.Errorf("database check %+v", db)
+			}
+			if db.Status == StatusDown && db.Error == "" {
+				t.Error("database check has no error")
+			}
+			for name, c := range report.Redacted().Checks {
+				if c.Error != "" {
+					t.Errorf("redacted %s check still has error %q", name, c.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"log/slog"
+	"time"
+
+	"vapiv/pkg/email"
+	"vapiv/pkg/notify"
+)
+
+// Template 是未渲染的通知，发送时按接收人的语言渲染 pkg/email/templates 下的同名模板
+type Template struct {
+	Event string
+	Name  string
+	Data  map[string]interface{}
+}
+
+func (t Template) render(locale string) notify.Message {
+	r, err := email.Render(t.Name, locale, t.Data)
+	if err != nil {
+		// 模板在启动时已解析，这里只会是数据与模板不匹配
+		slog.Error("render template failed", "template", t.Name, "locale", locale, "error", err)
+		return notify.Message{Event: t.Event, Subject: "VAPIV", Body: t.Name}
+	}
+	return notify.Message{Event: t.Event, Subject: r.Subject, Body: r.Text, HTML: r.HTML}
+}
+
+func CodeMessage(code string) Template {
+	return Template{Event: notify.EventVerification, Name: "code", Data: map[string]interface{}{"Code": code}}
+}
+
+func EmailChangedMessage(newEmail string) Template {
+	return Template{Event: notify.EventSecurity, Name: "email_changed", Data: map[string]interface{}{"NewEmail": newEmail}}
+}
+
+func PasswordChangedMessage() Template {
+	return Template{Event: notify.EventSecurity, Name: "password_changed"}
+}
+
+func InvitationMessage(orgName, role, token string) Template {
+	return Template{Event: notify.EventSecurity, Name: "invitation", Data: map[string]interface{}{
+		"OrgName": orgName,
+		"Role":    role,
+		"Token":   token,
+	}}
+}
+
+func LowBalanceMessage(balance, threshold int64) Template {
+	return Template{Event: notify.EventLowBalance, Name: "low_balance", Data: map[string]interface{}{
+		"Balance":   balance,
+		"Threshold": threshold,
+	}}
+}
+
+func KeyExpiryMessage(name, prefix string, expiresAt time.Time) Template {
+	return Template{Event: notify.EventKeyExpiry, Name: "key_expiry", Data: map[string]interface{}{
+		"Name":      name,
+		"Prefix":    prefix,
+		"ExpiresAt": expiresAt.UTC().Format("2006-01-02 15:04 UTC"),
+	}}
+}
+
+func TestMessage(event string) Template {
+	return Template{Event: event, Name: "test", Data: map[string]interface{}{"Event": event}}
+}
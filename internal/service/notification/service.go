@@ -0,0 +1,237 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/pkg/notify"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownEvent   = errors.New("unknown notification event")
+	ErrUnknownChannel = errors.New("notification channel not available")
+	ErrNotFound       = errors.New("notification setting not found")
+)
+
+const sendTimeout = 15 * time.Second
+
+type Service struct {
+	db       *gorm.DB
+	channels map[string]notify.Channel
+	// NotifyAsync 启动的 goroutine
+	pending sync.WaitGroup
+}
+
+func NewService(db *gorm.DB, channels ...notify.Channel) *Service {
+	m := make(map[string]notify.Channel, len(channels))
+	for _, ch := range channels {
+		m[ch.Name()] = ch
+	}
+	return &Service{db: db, channels: m}
+}
+
+// Channels 返回已启用的渠道名称
+func (s *Service) Channels() []string {
+	names := make([]string, 0, len(s.channels))
+	for name := range s.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SendTo 直接发送到指定地址，用于注册验证码、邀请等还没有账号或需要验证地址的场景。
+// locale 为空时使用该邮箱对应账号的语言
+func (s *Service) SendTo(ctx context.Context, channel, address, locale string, t Template) error {
+	ch, ok := s.channels[channel]
+	if !ok {
+		return ErrUnknownChannel
+	}
+	if locale == "" && channel == notify.ChannelEmail {
+		s.db.WithContext(ctx).Model(&model.User{}).Select("locale").Where("lower(email) = lower(?)", address).Scan(&locale)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return ch.Send(ctx, notify.Target{Address: address}, t.render(locale))
+}
+
+// Notify 按用户对 t.Event 的偏好发送到所有启用的渠道，没有设置时发送到账号邮箱
+func (s *Service) Notify(ctx context.Context, userID uint, t Template) error {
+	db := s.db.WithContext(ctx)
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	var settings []model.NotificationSetting
+	if err := db.Where("user_id = ? AND event = ?", userID, t.Event).Find(&settings).Error; err != nil {
+		return err
+	}
+	msg := t.render(user.Locale)
+	if len(settings) == 0 {
+		settings = []model.NotificationSetting{{Channel: notify.ChannelEmail, Enabled: true}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, st := range settings {
+		if !st.Enabled {
+			continue
+		}
+		ch, ok := s.channels[st.Channel]
+		if !ok {
+			continue
+		}
+		if err := ch.Send(ctx, target(&st, &user), msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", st.Channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyAsync 在后台发送，失败只记录日志。发送沿用 ctx 的 trace，但不会随请求结束而取消
+func (s *Service) NotifyAsync(ctx context.Context, userID uint, t Template) {
+	ctx = context.WithoutCancel(ctx)
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		if err := s.Notify(ctx, userID, t); err != nil {
+			slog.Error("notify user failed", "user_id", userID, "event", t.Event, "error", err)
+		}
+	}()
+}
+
+// Wait 等待 NotifyAsync 发出的通知完成，用于停止服务前
+func (s *Service) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) Settings(ctx context.Context, userID uint) ([]model.NotificationSetting, error) {
+	var settings []model.NotificationSetting
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("event, channel").Find(&settings).Error
+	return settings, err
+}
+
+// SetPreference 新增或更新一个事件的渠道设置，secret 为空时保留原值。
+// 邮件只能发送到已验证的账号邮箱，target 为空或与账号邮箱相同
+func (s *Service) SetPreference(ctx context.Context, userID uint, event, channel, target, secret string, enabled bool) (*model.NotificationSetting, error) {
+	if !validEvent(event) {
+		return nil, ErrUnknownEvent
+	}
+	ch, ok := s.channels[channel]
+	if !ok {
+		return nil, ErrUnknownChannel
+	}
+	db := s.db.WithContext(ctx)
+	if channel == notify.ChannelEmail {
+		var user model.User
+		if err := db.First(&user, userID).Error; err != nil {
+			return nil, err
+		}
+		if target != "" && !strings.EqualFold(target, user.Email) {
+			return nil, notify.ErrInvalidTarget
+		}
+		target = ""
+	}
+	if err := ch.Validate(notify.Target{Address: target, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	setting := &model.NotificationSetting{
+		UserID:  userID,
+		Event:   event,
+		Channel: channel,
+		Target:  target,
+		Secret:  secret,
+		Enabled: enabled,
+	}
+	update := []string{"target", "enabled", "updated_at"}
+	if secret != "" {
+		update = append(update, "secret")
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns(update),
+	}).Create(setting).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Where("user_id = ? AND event = ? AND channel = ?", userID, event, channel).First(setting).Error
+	return setting, err
+}
+
+func (s *Service) DeletePreference(ctx context.Context, userID, id uint) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.NotificationSetting{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Test 向一个已保存的设置发送测试消息
+func (s *Service) Test(ctx context.Context, userID, id uint) error {
+	db := s.db.WithContext(ctx)
+	var st model.NotificationSetting
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&st).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	ch, ok := s.channels[st.Channel]
+	if !ok {
+		return ErrUnknownChannel
+	}
+
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return ch.Send(ctx, target(&st, &user), TestMessage(st.Event).render(user.Locale))
+}
+
+// target 返回设置的接收地址，邮件渠道总是发送到当前的账号邮箱
+func target(st *model.NotificationSetting, user *model.User) notify.Target {
+	if st.Channel == notify.ChannelEmail {
+		return notify.Target{Address: user.Email}
+	}
+	return notify.Target{Address: st.Target, Secret: st.Secret}
+}
+
+func validEvent(event string) bool {
+	for _, e := range notify.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
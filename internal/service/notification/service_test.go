@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/pkg/notify"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeEmail 记录发送的地址
+type fakeEmail struct {
+	sent []string
+}
+
+func (f *fakeEmail) Name() string                 { return notify.ChannelEmail }
+func (f *fakeEmail) Validate(notify.Target) error { return nil }
+func (f *fakeEmail) Send(_ context.Context, to notify.Target, _ notify.Message) error {
+	f.sent = append(f.sent, to.Address)
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *gorm.DB, *fakeEmail) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.User{}, &model.NotificationSetting{}); err != nil {
+		t.Fatal(err)
+	}
+
+	email := &fakeEmail{}
+	return NewService(db, email), db, email
+}
+
+func TestEmailOnlyToAccountAddress(t *testing.T) {
+	ctx := context.Background()
+	s, db, email := newTestService(t)
+	u := model.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&u)
+
+	if _, err := s.SetPreference(ctx, u.ID, notify.EventSecurity, notify.ChannelEmail, "victim@example.com", "", true); !errors.Is(err, notify.ErrInvalidTarget) {
+		t.Errorf("foreign address: %v", err)
+	}
+	st, err := s.SetPreference(ctx, u.ID, notify.EventSecurity, notify.ChannelEmail, "Alice@Example.com", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Target != "" {
+		t.Errorf("stored target %q, want empty", st.Target)
+	}
+
+	// 旧版本保存的其他地址也不会被使用
+	db.Model(st).Update("target", "victim@example.com")
+	if err := s.Test(ctx, u.ID, st.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Notify(ctx, u.ID, PasswordChangedMessage()); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range email.sent {
+		if addr != u.Email {
+			t.Errorf("sent to %s, want %s", addr, u.Email)
+		}
+	}
+	if len(email.sent) != 2 {
+		t.Errorf("sent %d messages, want 2", len(email.sent))
+	}
+}
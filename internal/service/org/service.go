@@ -0,0 +1,281 @@
+package org
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/notification"
+	"vapiv/pkg/notify"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleDeveloper = "developer"
+	RoleBilling   = "billing"
+)
+
+var (
+	ErrNotMember         = errors.New("not a member of this organization")
+	ErrForbidden         = errors.New("insufficient organization role")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrLastOwner         = errors.New("organization must keep at least one owner")
+	ErrAlreadyMember     = errors.New("user is already a member")
+	ErrInvitationInvalid = errors.New("invitation is invalid or expired")
+	ErrInvalidPlan       = errors.New("invalid plan")
+)
+
+// 组织套餐，由管理员设置
+const (
+	PlanFree       = "free"
+	PlanTeam       = "team"
+	PlanEnterprise = "enterprise"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+type Service struct {
+	db        *gorm.DB
+	notifySvc *notification.Service
+}
+
+func NewService(db *gorm.DB, notifySvc *notification.Service) *Service {
+	return &Service{db: db, notifySvc: notifySvc}
+}
+
+func ValidPlan(plan string) bool {
+	switch plan {
+	case PlanFree, PlanTeam, PlanEnterprise:
+		return true
+	}
+	return false
+}
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleDeveloper, RoleBilling:
+		return true
+	}
+	return false
+}
+
+// Create 创建组织，创建者成为 owner
+func (s *Service) Create(ctx context.Context, userID uint, name string) (*model.Organization, error) {
+	org := &model.Organization{Name: name}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OrgMember{OrgID: org.ID, UserID: userID, Role: RoleOwner}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *Service) ListForUser(ctx context.Context, userID uint) ([]model.Organization, error) {
+	var orgs []model.Organization
+	err := s.db.WithContext(ctx).Joins("JOIN org_members ON org_members.org_id = organizations.id").
+		Where("org_members.user_id = ?", userID).
+		Find(&orgs).Error
+	return orgs, err
+}
+
+func (s *Service) Get(ctx context.Context, orgID uint) (*model.Organization, error) {
+	var org model.Organization
+	err := s.db.WithContext(ctx).First(&org, orgID).Error
+	return &org, err
+}
+
+// Authorize 校验用户是组织成员，且（如果指定）拥有其中一个角色
+func (s *Service) Authorize(ctx context.Context, orgID, userID uint, roles ...string) (*model.OrgMember, error) {
+	var member model.OrgMember
+	if err := s.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+
+	if len(roles) == 0 {
+		return &member, nil
+	}
+	for _, r := range roles {
+		if member.Role == r {
+			return &member, nil
+		}
+	}
+	return nil, ErrForbidden
+}
+
+func (s *Service) Members(ctx context.Context, orgID uint) ([]model.OrgMember, error) {
+	var members []model.OrgMember
+	err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("id").Find(&members).Error
+	return members, err
+}
+
+// Invite 创建邀请并发送邮件，只有 owner 可以邀请 owner
+func (s *Service) Invite(ctx context.Context, orgID, actorID uint, to, role string) (*model.OrgInvitation, error) {
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	actor, err := s.Authorize(ctx, orgID, actorID, RoleOwner, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if role == RoleOwner && actor.Role != RoleOwner {
+		return nil, ErrForbidden
+	}
+
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &model.OrgInvitation{
+		OrgID:     orgID,
+		Email:     strings.ToLower(to),
+		Role:      role,
+		Token:     generateToken(),
+		InvitedBy: actorID,
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	if err := s.db.WithContext(ctx).Create(inv).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.notifySvc.SendTo(ctx, notify.ChannelEmail, to, "", notification.InvitationMessage(org.Name, role, inv.Token)); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// AcceptInvitation 邀请只能由收件邮箱对应的用户接受
+func (s *Service) AcceptInvitation(ctx context.Context, userID uint, token string) (*model.OrgMember, error) {
+	db := s.db.WithContext(ctx)
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var inv model.OrgInvitation
+	err := db.Where("token = ? AND accepted_at IS NULL AND expires_at > ?", token, time.Now()).First(&inv).Error
+	if err != nil || !strings.EqualFold(inv.Email, user.Email) {
+		return nil, ErrInvitationInvalid
+	}
+
+	if _, err := s.Authorize(ctx, inv.OrgID, userID); err == nil {
+		return nil, ErrAlreadyMember
+	}
+
+	member := &model.OrgMember{OrgID: inv.OrgID, UserID: userID, Role: inv.Role}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Model(&inv).Update("accepted_at", &now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *Service) UpdateMemberRole(ctx context.Context, orgID, actorID, userID uint, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	actor, err := s.Authorize(ctx, orgID, actorID, RoleOwner, RoleAdmin)
+	if err != nil {
+		return err
+	}
+	member, err := s.Authorize(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	// 涉及 owner 的变更只能由 owner 操作
+	if (role == RoleOwner || member.Role == RoleOwner) && actor.Role != RoleOwner {
+		return ErrForbidden
+	}
+	if member.Role == RoleOwner && role != RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	return s.db.WithContext(ctx).Model(member).Update("role", role).Error
+}
+
+// SetPlan 修改组织套餐，返回修改前后的组织
+func (s *Service) SetPlan(ctx context.Context, orgID uint, plan string) (before, after *model.Organization, err error) {
+	if !ValidPlan(plan) {
+		return nil, nil, ErrInvalidPlan
+	}
+	if before, err = s.Get(ctx, orgID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&model.Organization{}).Where("id = ?", orgID).Update("plan", plan).Error; err != nil {
+		return nil, nil, err
+	}
+	after, err = s.Get(ctx, orgID)
+	return before, after, err
+}
+
+// RemoveMember 移除成员，成员也可以自己退出；该成员创建的组织 Key 同时删除
+func (s *Service) RemoveMember(ctx context.Context, orgID, actorID, userID uint) error {
+	member, err := s.Authorize(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if actorID != userID {
+		actor, err := s.Authorize(ctx, orgID, actorID, RoleOwner, RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if member.Role == RoleOwner && actor.Role != RoleOwner {
+			return ErrForbidden
+		}
+	}
+	if member.Role == RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&model.APIKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(member).Error
+	})
+}
+
+func (s *Service) Usage(ctx context.Context, orgID uint, limit int) ([]model.APIUsage, error) {
+	var usage []model.APIUsage
+	err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("id DESC").Limit(limit).Find(&usage).Error
+	return usage, err
+}
+
+func (s *Service) ensureAnotherOwner(ctx context.Context, orgID uint) error {
+	var count int64
+	s.db.WithContext(ctx).Model(&model.OrgMember{}).Where("org_id = ? AND role = ?", orgID, RoleOwner).Count(&count)
+	if count <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func generateToken() string {
+	bytes := make([]byte, 24)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
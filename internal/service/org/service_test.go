@@ -0,0 +1,190 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/notification"
+	"vapiv/pkg/notify"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeEmail 记录发送的邮件
+type fakeEmail struct {
+	sent []notify.Target
+}
+
+func (f *fakeEmail) Name() string                 { return notify.ChannelEmail }
+func (f *fakeEmail) Validate(notify.Target) error { return nil }
+func (f *fakeEmail) Send(_ context.Context, to notify.Target, _ notify.Message) error {
+	f.sent = append(f.sent, to)
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *gorm.DB, *fakeEmail) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.User{}, &model.APIKey{}, &model.Organization{}, &model.OrgMember{}, &model.OrgInvitation{}); err != nil {
+		t.Fatal(err)
+	}
+
+	email := &fakeEmail{}
+	return NewService(db, notification.NewService(db, email)), db, email
+}
+
+// newOrg 创建组织，users 依次为 owner、admin、developer、billing
+func newOrg(t *testing.T, s *Service, db *gorm.DB) (*model.Organization, []model.User) {
+	t.Helper()
+	users := make([]model.User, 4)
+	for i, name := range []string{"owner", "admin", "dev", "billing"} {
+		users[i] = model.User{Username: name, Email: name + "@example.com"}
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	o, err := s.Create(context.Background(), users[0].ID, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, role := range []string{RoleAdmin, RoleDeveloper, RoleBilling} {
+		db.Create(&model.OrgMember{OrgID: o.ID, UserID: users[i+1].ID, Role: role})
+	}
+	return o, users
+}
+
+func TestAuthorize(t *testing.T) {
+	s, db, _ := newTestService(t)
+	o, users := newOrg(t, s, db)
+	outsider := model.User{Username: "outsider", Email: "outsider@example.com"}
+	db.Create(&outsider)
+
+	tests := []struct {
+		user  uint
+		roles []string
+		want  error
+	}{
+		{users[0].ID, nil, nil},
+		{users[2].ID, []string{RoleOwner, RoleAdmin, RoleDeveloper}, nil},
+		{users[2].ID, []string{RoleOwner, RoleAdmin, RoleBilling}, ErrForbidden},
+		{users[3].ID, []string{RoleOwner, RoleAdmin, RoleBilling}, nil},
+		{outsider.ID, nil, ErrNotMember},
+	}
+	for _, tt := range tests {
+		if _, err := s.Authorize(context.Background(), o.ID, tt.user, tt.roles...); !errors.Is(err, tt.want) {
+			t.Errorf("user %d roles %v: err %v, want %v", tt.user, tt.roles, err, tt.want)
+		}
+	}
+}
+
+func TestMemberRoleChanges(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t)
+	o, users := newOrg(t, s, db)
+	owner, admin, dev := users[0].ID, users[1].ID, users[2].ID
+
+	if err := s.UpdateMemberRole(ctx, o.ID, dev, admin, RoleDeveloper); !errors.Is(err, ErrForbidden) {
+		t.Errorf("developer changes role: %v", err)
+	}
+	if err := s.UpdateMemberRole(ctx, o.ID, admin, dev, RoleOwner); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin promotes to owner: %v", err)
+	}
+	if err := s.UpdateMemberRole(ctx, o.ID, owner, owner, RoleAdmin); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("last owner steps down: %v", err)
+	}
+	if err := s.RemoveMember(ctx, o.ID, admin, owner); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin removes owner: %v", err)
+	}
+	if err := s.UpdateMemberRole(ctx, o.ID, admin, dev, RoleBilling); err != nil {
+		t.Errorf("admin changes developer role: %v", err)
+	}
+}
+
+func TestRemoveMemberDeletesOrgKeys(t *testing.T) {
+	s, db, _ := newTestService(t)
+	o, users := newOrg(t, s, db)
+	dev := users[2].ID
+
+	db.Create(&model.APIKey{UserID: dev, OrgID: &o.ID, Key: "org-key"})
+	db.Create(&model.APIKey{UserID: dev, Key: "personal-key"})
+
+	if err := s.RemoveMember(context.Background(), o.ID, dev, dev); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	db.Model(&model.APIKey{}).Where("user_id = ?", dev).Pluck("key", &keys)
+	if len(keys) != 1 || keys[0] != "personal-key" {
+		t.Errorf("remaining keys %v, want only the personal key", keys)
+	}
+}
+
+func TestInvitation(t *testing.T) {
+	ctx := context.Background()
+	s, db, email := newTestService(t)
+	o, users := newOrg(t, s, db)
+	admin, dev := users[1].ID, users[2].ID
+
+	if _, err := s.Invite(ctx, o.ID, dev, "new@example.com", RoleDeveloper); !errors.Is(err, ErrForbidden) {
+		t.Errorf("developer invites: %v", err)
+	}
+	if _, err := s.Invite(ctx, o.ID, admin, "new@example.com", RoleOwner); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin invites owner: %v", err)
+	}
+
+	inv, err := s.Invite(ctx, o.ID, admin, "New@Example.com", RoleDeveloper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(email.sent) != 1 || email.sent[0].Address != "New@Example.com" {
+		t.Errorf("sent %v", email.sent)
+	}
+
+	other := model.User{Username: "other", Email: "other@example.com"}
+	invitee := model.User{Username: "new", Email: "new@example.com"}
+	db.Create(&other)
+	db.Create(&invitee)
+
+	if _, err := s.AcceptInvitation(ctx, other.ID, inv.Token); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("accept with another email: %v", err)
+	}
+	member, err := s.AcceptInvitation(ctx, invitee.ID, inv.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Role != RoleDeveloper {
+		t.Errorf("role %s, want %s", member.Role, RoleDeveloper)
+	}
+	if _, err := s.AcceptInvitation(ctx, invitee.ID, inv.Token); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("accept twice: %v", err)
+	}
+}
+
+func TestSetPlan(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t)
+	o, _ := newOrg(t, s, db)
+
+	if o.Plan != PlanFree {
+		t.Errorf("default plan %q, want %q", o.Plan, PlanFree)
+	}
+	if _, _, err := s.SetPlan(ctx, o.ID, "gold"); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("invalid plan: %v", err)
+	}
+	before, after, err := s.SetPlan(ctx, o.ID, PlanTeam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Plan != PlanFree || after.Plan != PlanTeam {
+		t.Errorf("plan %q -> %q", before.Plan, after.Plan)
+	}
+}
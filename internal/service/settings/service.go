@@ -0,0 +1,343 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// changedChannel 用于在多个实例之间广播设置变更
+const changedChannel = "settings:changed"
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidValue   = errors.New("invalid setting value")
+)
+
+// 值的类型
+const (
+	TypeInt      = "int"
+	TypeDuration = "duration"
+	TypeBool     = "bool"
+)
+
+// 运行时设置的 key
+const (
+	RateLimitEnabled    = "ratelimit.enabled"
+	RateLimitRequests   = "ratelimit.requests"
+	RateLimitWindow     = "ratelimit.window"
+	DouyinTimeout       = "douyin.timeout"
+	BilibiliTimeout     = "bilibili.timeout"
+	IPTimeout           = "ip.timeout"
+	LowBalanceThreshold = "billing.low_balance_threshold"
+)
+
+// Definition 描述一项设置。Min、Max 对 int 和 duration（按纳秒）生效，为 0 表示不限制
+type Definition struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	Min         int64  `json:"-"`
+	Max         int64  `json:"-"`
+}
+
+// definitions 是全部可以在运行时修改的设置，新增设置时在这里定义
+var definitions = []Definition{
+	{Key: RateLimitEnabled, Type: TypeBool, Default: "true", Description: "是否按 IP 限制 /api 的请求频率"},
+	{Key: RateLimitRequests, Type: TypeInt, Default: "100", Description: "每个 IP 在一个窗口内允许的请求数", Min: 1},
+	{Key: RateLimitWindow, Type: TypeDuration, Default: "1m", Description: "限流窗口", Min: int64(time.Second), Max: int64(24 * time.Hour)},
+	{Key: DouyinTimeout, Type: TypeDuration, Default: "15s", Description: "请求抖音的超时时间", Min: int64(time.Second), Max: int64(time.Minute)},
+	{Key: BilibiliTimeout, Type: TypeDuration, Default: "15s", Description: "请求 B站的超时时间", Min: int64(time.Second), Max: int64(time.Minute)},
+	{Key: IPTimeout, Type: TypeDuration, Default: "15s", Description: "请求 ip-api 的超时时间", Min: int64(time.Second), Max: int64(time.Minute)},
+	{Key: LowBalanceThreshold, Type: TypeInt, Default: "100", Description: "余额低于该值时提醒，0 表示不提醒", Min: 0},
+}
+
+// Entry 是一项设置的当前状态
+type Entry struct {
+	Definition
+	Value     string     `json:"value"`
+	UpdatedBy uint       `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type value struct {
+	raw string
+	n   int64
+	b   bool
+}
+
+// Service 管理运行时设置，在内存中缓存当前值供中间件和 service 读取。
+// 修改后通过 Redis 通知其他实例重新加载，redis 不可用时仅本实例生效
+type Service struct {
+	db       *gorm.DB
+	rdb      *redis.Client
+	auditSvc *audit.Service
+
+	mu       sync.RWMutex
+	defs     map[string]Definition
+	values   map[string]value
+	modified map[string]model.Setting
+}
+
+func NewService(db *gorm.DB, rdb *redis.Client, auditSvc *audit.Service) *Service {
+	s := &Service{db: db, rdb: rdb, auditSvc: auditSvc, defs: map[string]Definition{}}
+	for _, d := range definitions {
+		v, _ := parse(d, d.Default)
+		d.Default = v.raw
+		s.defs[d.Key] = d
+	}
+	s.apply(nil)
+	return s
+}
+
+// SetDefault 修改默认值，用于由启动配置决定默认值的设置，需要在 Load 之前调用
+func (s *Service) SetDefault(key, raw string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.defs[key]
+	if !ok {
+		return ErrUnknownSetting
+	}
+	v, err := parse(d, raw)
+	if err != nil {
+		return err
+	}
+	d.Default = v.raw
+	s.defs[key] = d
+	s.values[key] = v
+	return nil
+}
+
+// Load 从数据库重新加载修改过的设置
+func (s *Service) Load(ctx context.Context) error {
+	var list []model.Setting
+	if err := s.db.WithContext(ctx).Find(&list).Error; err != nil {
+		return err
+	}
+	s.apply(list)
+	return nil
+}
+
+// apply 用数据库中的值覆盖默认值，无法解析的值（如定义修改后的旧数据）忽略
+func (s *Service) apply(list []model.Setting) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make(map[string]value, len(s.defs))
+	for key, d := range s.defs {
+		values[key], _ = parse(d, d.Default)
+	}
+	modified := make(map[string]model.Setting, len(list))
+	for _, st := range list {
+		d, ok := s.defs[st.Key]
+		if !ok {
+			continue
+		}
+		v, err := parse(d, st.Value)
+		if err != nil {
+			slog.Warn("ignore invalid setting", "key", st.Key, "value", st.Value, "error", err)
+			continue
+		}
+		values[st.Key] = v
+		modified[st.Key] = st
+	}
+	s.values = values
+	s.modified = modified
+}
+
+// Watch 订阅变更通知，收到后重新加载
+func (s *Service) Watch(ctx context.Context) {
+	if s.rdb == nil {
+		return
+	}
+
+	sub := s.rdb.Subscribe(ctx, changedChannel)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			if err := s.Load(ctx); err != nil {
+				slog.Error("reload settings failed", "error", err)
+			}
+		}
+	}
+}
+
+func (s *Service) get(key string) value {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[key]
+}
+
+// Int 返回 int 类型设置的当前值，key 未定义时返回 0
+func (s *Service) Int(key string) int64 {
+	return s.get(key).n
+}
+
+// Duration 返回 duration 类型设置的当前值，key 未定义时返回 0
+func (s *Service) Duration(key string) time.Duration {
+	return time.Duration(s.get(key).n)
+}
+
+// Bool 返回 bool 类型设置的当前值，key 未定义时返回 false
+func (s *Service) Bool(key string) bool {
+	return s.get(key).b
+}
+
+// List 返回全部设置及当前值，按 key 排序
+func (s *Service) List() []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Entry, 0, len(s.defs))
+	for key, d := range s.defs {
+		list = append(list, s.entry(key, d))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// entry 调用方持有 mu
+func (s *Service) entry(key string, d Definition) Entry {
+	e := Entry{Definition: d, Value: s.values[key].raw}
+	if st, ok := s.modified[key]; ok {
+		updatedAt := st.UpdatedAt
+		e.UpdatedBy = st.UpdatedBy
+		e.UpdatedAt = &updatedAt
+	}
+	return e
+}
+
+func (s *Service) find(key string) (Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.defs[key]
+	if !ok {
+		return Entry{}, ErrUnknownSetting
+	}
+	return s.entry(key, d), nil
+}
+
+// Set 校验并保存新值，立即对所有实例生效。操作人和修改前后的值记入审计日志
+func (s *Service) Set(ctx context.Context, key, raw string, meta audit.Meta) (*Entry, error) {
+	before, err := s.find(key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parse(before.Definition, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	st := model.Setting{Key: key, Value: v.raw, UpdatedBy: meta.ActorID}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&st).Error
+	if err != nil {
+		return nil, err
+	}
+	s.changed(ctx)
+
+	after, _ := s.find(key)
+	s.auditSvc.Record(ctx, meta, 0, audit.ActionSettingUpdate, auditValue(before), auditValue(after))
+	return &after, nil
+}
+
+// Reset 删除修改过的值，恢复为默认值
+func (s *Service) Reset(ctx context.Context, key string, meta audit.Meta) (*Entry, error) {
+	before, err := s.find(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := s.db.WithContext(ctx).Where("key = ?", key).Delete(&model.Setting{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	s.changed(ctx)
+
+	after, _ := s.find(key)
+	if res.RowsAffected > 0 {
+		s.auditSvc.Record(ctx, meta, 0, audit.ActionSettingReset, auditValue(before), auditValue(after))
+	}
+	return &after, nil
+}
+
+// changed 刷新本地缓存并通知其他实例。数据库已经写入，刷新失败只记录日志，
+// 本实例在下次收到变更通知或重启时加载新值
+func (s *Service) changed(ctx context.Context) {
+	if err := s.Load(ctx); err != nil {
+		slog.Error("reload settings failed", "error", err)
+	}
+	if s.rdb != nil {
+		if err := s.rdb.Publish(ctx, changedChannel, "reload").Err(); err != nil {
+			slog.Error("publish settings change failed", "error", err)
+		}
+	}
+}
+
+func auditValue(e Entry) map[string]string {
+	return map[string]string{"key": e.Key, "value": e.Value}
+}
+
+// parse 按定义解析并规范化值，例如 60s 规范化为 1m0s
+func parse(d Definition, raw string) (value, error) {
+	invalid := func(format string, args ...interface{}) (value, error) {
+		return value{}, fmt.Errorf("%w: %s: %s", ErrInvalidValue, d.Key, fmt.Sprintf(format, args...))
+	}
+
+	switch d.Type {
+	case TypeBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid("expected true or false, got %q", raw)
+		}
+		return value{raw: strconv.FormatBool(b), b: b}, nil
+	case TypeInt:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return invalid("expected an integer, got %q", raw)
+		}
+		if n < d.Min || (d.Max != 0 && n > d.Max) {
+			return invalid("%s", rangeMessage(d, strconv.FormatInt))
+		}
+		return value{raw: strconv.FormatInt(n, 10), n: n}, nil
+	case TypeDuration:
+		dur, err := time.ParseDuration(raw)
+		if err != nil {
+			return invalid("expected a duration like 30s or 5m, got %q", raw)
+		}
+		if int64(dur) < d.Min || (d.Max != 0 && int64(dur) > d.Max) {
+			return invalid("%s", rangeMessage(d, func(n int64, _ int) string { return time.Duration(n).String() }))
+		}
+		return value{raw: dur.String(), n: int64(dur)}, nil
+	}
+	return invalid("unsupported type %s", d.Type)
+}
+
+func rangeMessage(d Definition, format func(int64, int) string) string {
+	if d.Max == 0 {
+		return "must be at least " + format(d.Min, 10)
+	}
+	return "must be between " + format(d.Min, 10) + " and " + format(d.Max, 10)
+}
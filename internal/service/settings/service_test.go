@@ -0,0 +1,149 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.Setting{}, &model.AuditEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db, nil, audit.NewService(db))
+}
+
+func TestParse(t *testing.T) {
+	defs := map[string]Definition{}
+	for _, d := range definitions {
+		defs[d.Key] = d
+	}
+
+	tests := []struct {
+		key  string
+		raw  string
+		want string
+		// 出错时错误信息中应包含的内容，为空表示应当成功
+		err string
+	}{
+		{RateLimitEnabled, "1", "true", ""},
+		{RateLimitEnabled, "FALSE", "false", ""},
+		{RateLimitEnabled, "yes", "", "expected true or false"},
+		{RateLimitRequests, "5", "5", ""},
+		{RateLimitRequests, "0", "", "must be at least 1"},
+		{RateLimitRequests, "1.5", "", "expected an integer"},
+		{LowBalanceThreshold, "0", "0", ""},
+		{RateLimitWindow, "60s", "1m0s", ""},
+		{RateLimitWindow, "24h", "24h0m0s", ""},
+		{RateLimitWindow, "500ms", "", "must be between 1s and 24h0m0s"},
+		{RateLimitWindow, "25h", "", "must be between 1s and 24h0m0s"},
+		{RateLimitWindow, "5", "", "expected a duration"},
+		{DouyinTimeout, "2m", "", "must be between 1s and 1m0s"},
+	}
+	for _, tt := range tests {
+		v, err := parse(defs[tt.key], tt.raw)
+		if tt.err != "" {
+			if !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), tt.err) || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("parse %s=%q: error %v, want %q", tt.key, tt.raw, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || v.raw != tt.want {
+			t.Errorf("parse %s=%q = %q, %v, want %q", tt.key, tt.raw, v.raw, err, tt.want)
+		}
+	}
+
+	if _, err := parse(Definition{Key: "x", Type: "float"}, "1"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("unsupported type: %v", err)
+	}
+}
+
+// 默认值都必须能通过自己的校验
+func TestDefaultsAreValid(t *testing.T) {
+	for _, d := range definitions {
+		if _, err := parse(d, d.Default); err != nil {
+			t.Errorf("default of %s: %v", d.Key, err)
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	if err := s.SetDefault("missing", "1"); !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("unknown key: %v", err)
+	}
+	if err := s.SetDefault(RateLimitWindow, "1ms"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("out of range default: %v", err)
+	}
+	if s.Duration(RateLimitWindow) != time.Minute {
+		t.Errorf("window %v after a rejected default, want 1m", s.Duration(RateLimitWindow))
+	}
+
+	if err := s.SetDefault(RateLimitWindow, "120s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := s.find(RateLimitWindow)
+	if s.Duration(RateLimitWindow) != 2*time.Minute || e.Default != "2m0s" || e.Value != "2m0s" {
+		t.Errorf("after SetDefault: %v, entry %+v", s.Duration(RateLimitWindow), e)
+	}
+
+	// 修改过的值优先于默认值，恢复时回到 SetDefault 设置的默认值
+	if _, err := s.Set(ctx, RateLimitWindow, "30s", audit.Meta{ActorID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Duration(RateLimitWindow) != 30*time.Second {
+		t.Errorf("after Set: %v, want 30s", s.Duration(RateLimitWindow))
+	}
+	if _, err := s.Reset(ctx, RateLimitWindow, audit.Meta{ActorID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Duration(RateLimitWindow) != 2*time.Minute {
+		t.Errorf("after Reset: %v, want 2m", s.Duration(RateLimitWindow))
+	}
+}
+
+func TestSetRejectsInvalidValue(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	if _, err := s.Set(ctx, RateLimitRequests, "0", audit.Meta{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("below min: %v", err)
+	}
+	if _, err := s.Set(ctx, "missing", "1", audit.Meta{}); !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("unknown key: %v", err)
+	}
+	if s.Int(RateLimitRequests) != 100 {
+		t.Errorf("requests %d after rejected updates, want 100", s.Int(RateLimitRequests))
+	}
+
+	// 数据库中无法解析的旧值被忽略
+	s.db.Create(&model.Setting{Key: RateLimitRequests, Value: "-1"})
+	if err := s.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.Int(RateLimitRequests) != 100 {
+		t.Errorf("requests %d after loading an invalid value, want 100", s.Int(RateLimitRequests))
+	}
+}
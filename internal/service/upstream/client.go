@@ -0,0 +1,67 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"vapiv/internal/metrics"
+	"vapiv/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Do 发送对第三方服务的请求，记录耗时、结果和 span，并计入 Stats。连接失败、5xx、403 和 429
+// 记为失败，返回的错误已按 ErrUnavailable 包装；状态码的判断仍由调用方通过 CheckStatus 完成。
+// span 的父级来自 req 的 context，并通过 traceparent 请求头向下游传播
+func Do(client *http.Client, name, op string, req *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, span := tracing.Tracer().Start(req.Context(), name+" "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("upstream.name", name),
+			attribute.String("upstream.op", op),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		metrics.ObserveUpstream(name, op, time.Since(start), false)
+		record(name, false)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, Wrap(name, op, ErrUnavailable, err)
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	ok := resp.StatusCode < 500 && resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests
+	if !ok {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	metrics.ObserveUpstream(name, op, time.Since(start), ok)
+	record(name, ok)
+	return resp, nil
+}
+
+// Get 是不需要设置请求头时的 Do
+func Get(ctx context.Context, client *http.Client, name, op, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, Wrap(name, op, ErrInvalidInput, err)
+	}
+	return Do(client, name, op, req)
+}
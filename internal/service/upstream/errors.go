@@ -0,0 +1,59 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 调用第三方服务（B站、抖音、ip-api 等）失败的原因，handler 根据它们选择错误码
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrNotFound     = errors.New("not found")
+	ErrUnavailable  = errors.New("upstream unavailable")
+	ErrBlocked      = errors.New("blocked by upstream")
+	ErrParse        = errors.New("failed to parse upstream response")
+)
+
+// Error 记录出错的上游和步骤。Kind 是上面的错误之一，Err 是原始错误，
+// 两者都可以用 errors.Is 判断；Error() 包含上游细节，只应写入日志
+type Error struct {
+	Upstream string
+	Op       string
+	Kind     error
+	Err      error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s %s: %v", e.Upstream, e.Op, e.Kind)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+func Wrap(upstream, op string, kind, err error) error {
+	return &Error{Upstream: upstream, Op: op, Kind: kind, Err: err}
+}
+
+// CheckStatus 把非 2xx 响应转换为错误：404 为 ErrNotFound，403 和 429 为 ErrBlocked，其余为 ErrUnavailable
+func CheckStatus(upstream, op string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	kind := ErrUnavailable
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		kind = ErrBlocked
+	}
+	return Wrap(upstream, op, kind, fmt.Errorf("status %d", resp.StatusCode))
+}
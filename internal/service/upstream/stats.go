@@ -0,0 +1,111 @@
+package upstream
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// 每个上游保留最近的调用结果数量
+	statsWindow = 100
+	// 早于该时间的调用不参与统计
+	statsMaxAge = 10 * time.Minute
+)
+
+// 可达性状态
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+)
+
+type result struct {
+	at time.Time
+	ok bool
+}
+
+// window 是一个上游最近调用结果的环形缓冲区
+type window struct {
+	results [statsWindow]result
+	next    int
+	count   int
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*window{}
+)
+
+func record(name string, ok bool) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	w := stats[name]
+	if w == nil {
+		w = &window{}
+		stats[name] = w
+	}
+	w.results[w.next] = result{at: time.Now(), ok: ok}
+	w.next = (w.next + 1) % statsWindow
+	if w.count < statsWindow {
+		w.count++
+	}
+}
+
+// Reachability 是根据最近调用结果估计的上游可达性
+type Reachability struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Calls       int        `json:"calls"`
+	Failures    int        `json:"failures"`
+	SuccessRate float64    `json:"success_rate"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+}
+
+// Stats 返回最近 10 分钟内（每个上游最多 100 次）调用的成功率。
+// 成功率不低于 90% 为 up，不低于 50% 为 degraded，否则为 down；没有调用时为 unknown
+func Stats() []Reachability {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	since := time.Now().Add(-statsMaxAge)
+	list := make([]Reachability, 0, len(stats))
+	for name, w := range stats {
+		r := Reachability{Name: name, Status: StatusUnknown}
+		for i := 0; i < w.count; i++ {
+			res := w.results[i]
+			if res.at.Before(since) {
+				continue
+			}
+			at := res.at
+			r.Calls++
+			if res.ok {
+				if r.LastSuccess == nil || at.After(*r.LastSuccess) {
+					r.LastSuccess = &at
+				}
+			} else {
+				r.Failures++
+				if r.LastFailure == nil || at.After(*r.LastFailure) {
+					r.LastFailure = &at
+				}
+			}
+		}
+		if r.Calls > 0 {
+			r.SuccessRate = float64(r.Calls-r.Failures) / float64(r.Calls)
+			switch {
+			case r.SuccessRate >= 0.9:
+				r.Status = StatusUp
+			case r.SuccessRate >= 0.5:
+				r.Status = StatusDegraded
+			default:
+				r.Status = StatusDown
+			}
+		}
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,291 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/notification"
+	"vapiv/pkg/notify"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var ErrSoleOwner = errors.New("transfer organization ownership before deleting the account")
+
+// ExportedKey 是不含完整值的 API Key，UserID 是创建者
+type ExportedKey struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	OrgID     *uint      `json:"org_id,omitempty"`
+	Status    int        `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func exportKey(k model.APIKey) ExportedKey {
+	return ExportedKey{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		Name:      k.Name,
+		Prefix:    keyPrefix(k.Key),
+		OrgID:     k.OrgID,
+		Status:    k.Status,
+		ExpiresAt: k.ExpiresAt,
+		CreatedAt: k.CreatedAt,
+	}
+}
+
+// ExportData 是用户可以导出的全部个人数据。AuditEvents 包含用户本人和针对该用户的操作，
+// Invitations 包含发给该邮箱和该用户发出的组织邀请
+type ExportData struct {
+	ExportedAt    time.Time                   `json:"exported_at"`
+	Profile       *model.User                 `json:"profile"`
+	APIKeys       []ExportedKey               `json:"api_keys"`
+	Usage         []model.APIUsage            `json:"usage"`
+	Memberships   []model.OrgMember           `json:"memberships"`
+	Identities    []model.UserIdentity        `json:"identities"`
+	AuditEvents   []model.AuditEvent          `json:"audit_events"`
+	Notifications []model.NotificationSetting `json:"notification_settings"`
+	Invitations   []model.OrgInvitation       `json:"invitations"`
+}
+
+func (s *Service) Export(ctx context.Context, userID uint) (*ExportData, error) {
+	data := &ExportData{ExportedAt: time.Now()}
+
+	var err error
+	if data.Profile, err = s.GetProfile(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db := s.db.WithContext(ctx)
+	var keys []model.APIKey
+	if err := db.Where("user_id = ?", userID).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	data.APIKeys = make([]ExportedKey, 0, len(keys))
+	for _, k := range keys {
+		data.APIKeys = append(data.APIKeys, exportKey(k))
+	}
+
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&data.Usage).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Find(&data.Memberships).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Find(&data.Identities).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ? OR actor_id = ?", userID, userID).Order("id").Find(&data.AuditEvents).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("event, channel").Find(&data.Notifications).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("lower(email) = ? OR invited_by = ?", normalizeEmail(data.Profile.Email), userID).
+		Order("id").Find(&data.Invitations).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ExportZip 把导出数据按类别拆分成多个 JSON 文件打包
+func (s *Service) ExportZip(ctx context.Context, userID uint) ([]byte, error) {
+	data, err := s.Export(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]interface{}{
+		"profile.json":               data.Profile,
+		"api_keys.json":              data.APIKeys,
+		"usage.json":                 data.Usage,
+		"memberships.json":           data.Memberships,
+		"identities.json":            data.Identities,
+		"audit_events.json":          data.AuditEvents,
+		"notification_settings.json": data.Notifications,
+		"invitations.json":           data.Invitations,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, v := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Service) CheckPassword(ctx context.Context, userID uint, password string) bool {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+}
+
+// SendDeleteCode 注销验证码总是发送到账号邮箱，不受通知偏好影响
+func (s *Service) SendDeleteCode(ctx context.Context, userID uint) error {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	code, err := s.captchaSvc.Generate(ctx, user.Email, "delete")
+	if err != nil {
+		return err
+	}
+	return s.notifySvc.SendTo(ctx, notify.ChannelEmail, user.Email, user.Locale, notification.CodeMessage(code))
+}
+
+func (s *Service) VerifyDeleteCode(ctx context.Context, userID uint, code string) bool {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return s.VerifyCode(ctx, user.Email, "delete", code)
+}
+
+// DeleteAccount 吊销所有 Key、匿名化调用记录并软删除用户，用户名和邮箱立即释放，
+// 数据在保留期后由 PurgeDeleted 彻底删除
+func (s *Service) DeleteAccount(ctx context.Context, userID uint, meta audit.Meta) error {
+	var soleOwner int64
+	err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM org_members m WHERE m.user_id = ? AND m.role = 'owner'
+		AND NOT EXISTS (SELECT 1 FROM org_members o WHERE o.org_id = m.org_id AND o.role = 'owner' AND o.user_id <> m.user_id)`,
+		userID).Scan(&soleOwner).Error
+	if err != nil {
+		return err
+	}
+	if soleOwner > 0 {
+		return ErrSoleOwner
+	}
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.APIKey{}).Where("user_id = ?", userID).Update("status", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.APIUsage{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"user_id": 0, "ip": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.OrgMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		// 发给该邮箱的邀请在邮箱替换后无法再接受
+		if err := tx.Where("email = ?", user.Email).Delete(&model.OrgInvitation{}).Error; err != nil {
+			return err
+		}
+		// 软删除的行仍然占用唯一索引，替换用户名和邮箱以便重新注册。
+		// 用户名包含 @，不会与注册的用户名冲突；.invalid 域名的邮箱无法收到验证码
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"status":   0,
+			"username": fmt.Sprintf("deleted@%d", userID),
+			"email":    fmt.Sprintf("%d@deleted.invalid", userID),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, userID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, meta, userID, audit.ActionAccountDelete, nil, nil)
+	return nil
+}
+
+// PurgeDeleted 彻底删除软删除时间早于保留期的用户及其 Key 和通知设置，
+// 审计日志只保留用户 ID 和操作，清除 IP、User-Agent 和变更内容，发出的邀请去掉邀请人
+func (s *Service) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+
+	var ids []uint
+	err := s.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id IN ?", ids).Delete(&model.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&model.NotificationSetting{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.AuditEvent{}).Where("user_id IN ?", ids).
+			Updates(map[string]interface{}{"ip": "", "user_agent": "", "before": "", "after": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.AuditEvent{}).Where("actor_id IN ?", ids).
+			Updates(map[string]interface{}{"ip": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.OrgInvitation{}).Where("invited_by IN ?", ids).Update("invited_by", 0).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.User{}, ids).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// RunPurge 每天执行一次 PurgeDeleted，直到 ctx 结束
+func (s *Service) RunPurge(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.PurgeDeleted(ctx, retention); err != nil {
+			slog.Error("purge deleted users failed", "error", err)
+		} else if n > 0 {
+			slog.Info("purged deleted users", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func keyPrefix(key string) string {
+	if len(key) <= 13 {
+		return key
+	}
+	return key[:13] + "..."
+}
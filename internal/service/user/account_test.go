@@ -0,0 +1,152 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+
+	"gorm.io/gorm"
+)
+
+// migrateAccount 创建注销和导出涉及的其他表
+func migrateAccount(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.AutoMigrate(&model.OrgMember{}, &model.OrgInvitation{}, &model.UserIdentity{}, &model.APIUsage{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteAccountSoleOwner(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t, Registration{Mode: RegistrationOpen})
+	u := model.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&u)
+
+	// 查询失败时不能当作没有需要转让的组织
+	if err := s.DeleteAccount(ctx, u.ID, audit.Meta{}); err == nil {
+		t.Fatal("delete succeeded without org_members table")
+	}
+
+	migrateAccount(t, db)
+	db.Create(&model.OrgMember{OrgID: 1, UserID: u.ID, Role: "owner"})
+	if err := s.DeleteAccount(ctx, u.ID, audit.Meta{}); !errors.Is(err, ErrSoleOwner) {
+		t.Errorf("sole owner: %v", err)
+	}
+
+	db.Create(&model.OrgMember{OrgID: 1, UserID: u.ID + 1, Role: "owner"})
+	if err := s.DeleteAccount(ctx, u.ID, audit.Meta{}); err != nil {
+		t.Errorf("co-owner: %v", err)
+	}
+}
+
+func TestReRegisterAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t, Registration{Mode: RegistrationOpen})
+	migrateAccount(t, db)
+	u, err := s.Register(ctx, "alice", "alice@example.com", "password", "en", "", audit.Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteAccount(ctx, u.ID, audit.Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Register(ctx, "alice", "alice@example.com", "password", "en", "", audit.Meta{}); err != nil {
+		t.Errorf("re-register after delete: %v", err)
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t, Registration{Mode: RegistrationOpen})
+	migrateAccount(t, db)
+	u, err := s.Register(ctx, "alice", "alice@example.com", "password", "en", "", audit.Meta{IP: "10.0.0.1", UserAgent: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&model.NotificationSetting{UserID: u.ID, Event: "security", Channel: "email", Enabled: true})
+	db.Create(&model.OrgInvitation{OrgID: 1, Email: "bob@example.com", Token: "sent", InvitedBy: u.ID})
+	db.Create(&model.OrgInvitation{OrgID: 1, Email: "alice@example.com", Token: "received", InvitedBy: 99})
+
+	if err := s.DeleteAccount(ctx, u.ID, audit.Meta{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&model.OrgInvitation{}).Where("email = ?", "alice@example.com").Count(&count)
+	if count != 0 {
+		t.Errorf("%d invitations to the deleted email left", count)
+	}
+
+	// 保留期内不清除
+	if n, err := s.PurgeDeleted(ctx, time.Hour); err != nil || n != 0 {
+		t.Fatalf("purged %d within retention: %v", n, err)
+	}
+	if n, err := s.PurgeDeleted(ctx, -time.Hour); err != nil || n != 1 {
+		t.Fatalf("purged %d: %v", n, err)
+	}
+
+	db.Unscoped().Model(&model.User{}).Where("id = ?", u.ID).Count(&count)
+	if count != 0 {
+		t.Error("user row left")
+	}
+	db.Model(&model.NotificationSetting{}).Where("user_id = ?", u.ID).Count(&count)
+	if count != 0 {
+		t.Error("notification settings left")
+	}
+	var events []model.AuditEvent
+	db.Where("user_id = ?", u.ID).Find(&events)
+	if len(events) == 0 {
+		t.Fatal("audit events should be kept")
+	}
+	for _, e := range events {
+		if e.IP != "" || e.UserAgent != "" || e.Before != "" || e.After != "" {
+			t.Errorf("audit event %s not anonymised: %+v", e.Action, e)
+		}
+	}
+	var inv model.OrgInvitation
+	db.Where("token = ?", "sent").First(&inv)
+	if inv.InvitedBy != 0 {
+		t.Errorf("invitation still references the purged user")
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t, Registration{Mode: RegistrationOpen})
+	migrateAccount(t, db)
+	u, err := s.Register(ctx, "alice", "alice@example.com", "password", "en", "", audit.Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&model.NotificationSetting{UserID: u.ID, Event: "security", Channel: "email", Enabled: true, Secret: "s3cret"})
+	db.Create(&model.OrgInvitation{OrgID: 1, Email: "bob@example.com", Token: "sent", InvitedBy: u.ID})
+	db.Create(&model.OrgInvitation{OrgID: 2, Email: "alice@example.com", Token: "received", InvitedBy: 99})
+	db.Create(&model.OrgInvitation{OrgID: 3, Email: "carol@example.com", Token: "other", InvitedBy: 99})
+
+	data, err := s.Export(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.AuditEvents) != 1 || data.AuditEvents[0].Action != audit.ActionRegister {
+		t.Errorf("audit events %+v", data.AuditEvents)
+	}
+	if len(data.Notifications) != 1 {
+		t.Errorf("notification settings %+v", data.Notifications)
+	}
+	if len(data.Invitations) != 2 {
+		t.Errorf("invitations %+v", data.Invitations)
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(body, []byte("s3cret")) {
+		t.Error("export contains the notification secret")
+	}
+}
@@ -1,42 +1,177 @@
 package user
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
 
 	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/notification"
+
+	"gorm.io/gorm"
 )
 
-func (s *Service) CreateAPIKey(userID uint, name string) (*model.APIKey, error) {
+var ErrKeyNotFound = errors.New("api key not found")
+
+// CreateAPIKey 创建 API Key，orgID 不为空时 Key 归组织所有，userID 记录创建者；expiresAt 为空时永不过期
+func (s *Service) CreateAPIKey(ctx context.Context, userID uint, orgID *uint, name string, expiresAt *time.Time, meta audit.Meta) (*model.APIKey, error) {
 	key := generateAPIKey()
 	apiKey := &model.APIKey{
-		UserID: userID,
-		Key:    key,
-		Name:   name,
+		UserID:    userID,
+		OrgID:     orgID,
+		Key:       key,
+		Name:      name,
+		ExpiresAt: expiresAt,
 	}
 
-	if err := s.db.Create(apiKey).Error; err != nil {
+	if err := s.db.WithContext(ctx).Create(apiKey).Error; err != nil {
 		return nil, err
 	}
+	s.auditSvc.Record(ctx, meta, userID, audit.ActionAPIKeyCreate, nil, keyState(apiKey))
 	return apiKey, nil
 }
 
-func (s *Service) ListAPIKeys(userID uint) ([]model.APIKey, error) {
+func (s *Service) ListAPIKeys(ctx context.Context, userID uint) ([]model.APIKey, error) {
 	var keys []model.APIKey
-	err := s.db.Where("user_id = ?", userID).Find(&keys).Error
+	err := s.db.WithContext(ctx).Where("user_id = ? AND org_id IS NULL", userID).Find(&keys).Error
 	return keys, err
 }
 
-func (s *Service) DeleteAPIKey(userID, keyID uint) error {
-	return s.db.Where("id = ? AND user_id = ?", keyID, userID).Delete(&model.APIKey{}).Error
+func (s *Service) DeleteAPIKey(ctx context.Context, userID, keyID uint, meta audit.Meta) error {
+	return s.deleteKey(ctx, meta, "id = ? AND user_id = ? AND org_id IS NULL", keyID, userID)
 }
 
-func (s *Service) GetProfile(userID uint) (*model.User, error) {
+// ListOrgAPIKeys 所有成员都可以查看，只返回前缀，完整的值只在创建和轮换时返回
+func (s *Service) ListOrgAPIKeys(ctx context.Context, orgID uint) ([]ExportedKey, error) {
+	var keys []model.APIKey
+	if err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	list := make([]ExportedKey, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, exportKey(k))
+	}
+	return list, nil
+}
+
+func (s *Service) DeleteOrgAPIKey(ctx context.Context, orgID, keyID uint, meta audit.Meta) error {
+	return s.deleteKey(ctx, meta, "id = ? AND org_id = ?", keyID, orgID)
+}
+
+// RotateAPIKey 为已有 Key 生成新的值，旧值立即失效
+func (s *Service) RotateAPIKey(ctx context.Context, userID, keyID uint, meta audit.Meta) (*model.APIKey, error) {
+	return s.rotateKey(ctx, meta, "id = ? AND user_id = ? AND org_id IS NULL", keyID, userID)
+}
+
+func (s *Service) RotateOrgAPIKey(ctx context.Context, orgID, keyID uint, meta audit.Meta) (*model.APIKey, error) {
+	return s.rotateKey(ctx, meta, "id = ? AND org_id = ?", keyID, orgID)
+}
+
+func (s *Service) deleteKey(ctx context.Context, meta audit.Meta, query string, args ...interface{}) error {
+	var key model.APIKey
+	if err := s.db.WithContext(ctx).Where(query, args...).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(&key).Error; err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, meta, key.UserID, audit.ActionAPIKeyDelete, keyState(&key), nil)
+	return nil
+}
+
+func (s *Service) rotateKey(ctx context.Context, meta audit.Meta, query string, args ...interface{}) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := s.db.WithContext(ctx).Where(query, args...).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	before := keyState(&key)
+	key.Key = generateAPIKey()
+	if err := s.db.WithContext(ctx).Model(&key).Update("key", key.Key).Error; err != nil {
+		return nil, err
+	}
+	s.auditSvc.Record(ctx, meta, key.UserID, audit.ActionAPIKeyRotate, before, keyState(&key))
+	return &key, nil
+}
+
+// keyExpiryNotice 在 Key 到期前多久发送提醒
+const keyExpiryNotice = 7 * 24 * time.Hour
+
+// NotifyExpiringKeys 向即将到期的 Key 的创建者发送一次提醒，返回提醒的 Key 数量。
+// 多个实例同时执行时通过条件更新保证只提醒一次
+func (s *Service) NotifyExpiringKeys(ctx context.Context) (int, error) {
+	now := time.Now()
+	var keys []model.APIKey
+	err := s.db.WithContext(ctx).Where("status = 1 AND expiry_notified_at IS NULL").
+		Where("expires_at > ? AND expires_at <= ?", now, now.Add(keyExpiryNotice)).
+		Find(&keys).Error
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, k := range keys {
+		res := s.db.WithContext(ctx).Model(&model.APIKey{}).
+			Where("id = ? AND expiry_notified_at IS NULL", k.ID).
+			Update("expiry_notified_at", now)
+		if res.Error != nil {
+			return n, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		s.notifySvc.NotifyAsync(ctx, k.UserID, notification.KeyExpiryMessage(k.Name, keyPrefix(k.Key), *k.ExpiresAt))
+		n++
+	}
+	return n, nil
+}
+
+// RunKeyExpiry 每小时执行一次 NotifyExpiringKeys，直到 ctx 结束
+func (s *Service) RunKeyExpiry(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.NotifyExpiringKeys(ctx); err != nil {
+			slog.Error("notify expiring api keys failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) GetProfile(ctx context.Context, userID uint) (*model.User, error) {
 	var user model.User
-	err := s.db.First(&user, userID).Error
+	err := s.db.WithContext(ctx).First(&user, userID).Error
 	return &user, err
 }
 
+// keyState 审计日志中只记录 Key 的前缀
+func keyState(k *model.APIKey) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     k.ID,
+		"name":   k.Name,
+		"prefix": keyPrefix(k.Key),
+		"org_id": k.OrgID,
+		"status": k.Status,
+	}
+}
+
 func generateAPIKey() string {
 	bytes := make([]byte, 32)
 	rand.Read(bytes)
@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/notification"
+	"vapiv/pkg/captcha"
+	"vapiv/pkg/notify"
+	"vapiv/pkg/token"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeEmail 记录发送的消息
+type fakeEmail struct {
+	sent chan notify.Message
+}
+
+func (f *fakeEmail) Name() string                 { return notify.ChannelEmail }
+func (f *fakeEmail) Validate(notify.Target) error { return nil }
+func (f *fakeEmail) Send(_ context.Context, _ notify.Target, msg notify.Message) error {
+	f.sent <- msg
+	return nil
+}
+
+func newTestService(t *testing.T, reg Registration) (*Service, *gorm.DB, *fakeEmail) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	// 每个连接是一个新的内存数据库，只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.User{}, &model.APIKey{}, &model.AuditEvent{}, &model.NotificationSetting{}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := token.NewKeySet(nil, nil, "vapiv", "vapiv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := &fakeEmail{sent: make(chan notify.Message, 10)}
+	svc := NewService(db, keys, 1, notification.NewService(db, email), captcha.NewService(nil), audit.NewService(db), reg)
+	return svc, db, email
+}
+
+func TestNotifyExpiringKeys(t *testing.T) {
+	ctx := context.Background()
+	s, db, email := newTestService(t, Registration{Mode: RegistrationOpen})
+	u := model.User{Username: "alice", Email: "alice@example.com"}
+	db.Create(&u)
+
+	day := 24 * time.Hour
+	for name, in := range map[string]time.Duration{"soon": 3 * day, "later": 30 * day, "expired": -day} {
+		expiresAt := time.Now().Add(in)
+		if _, err := s.CreateAPIKey(ctx, u.ID, nil, name, &expiresAt, audit.Meta{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateAPIKey(ctx, u.ID, nil, "never", nil, audit.Meta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.NotifyExpiringKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("notified %d keys, want 1", n)
+	}
+	select {
+	case msg := <-email.sent:
+		if msg.Event != notify.EventKeyExpiry {
+			t.Errorf("event %s", msg.Event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no notification sent")
+	}
+
+	// 同一个 Key 只提醒一次
+	if n, _ := s.NotifyExpiringKeys(ctx); n != 0 {
+		t.Errorf("second run notified %d keys", n)
+	}
+}
@@ -0,0 +1,183 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/pkg/oidc"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityLinked = errors.New("external identity is linked to another account")
+	ErrEmailRequired  = errors.New("identity provider did not return an email")
+	// 未验证的邮箱可以是任意地址，不能用来注册、关联已有账号或通过域名限制
+	ErrEmailUnverified = errors.New("identity provider has not verified the email")
+	ErrUserDisabled    = errors.New("user is disabled")
+)
+
+var usernameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// LoginWithIdentity 用外部身份登录并返回 JWT。
+// linkUserID 不为 0 时把身份关联到该用户；否则按已关联身份、已验证邮箱的顺序查找用户，
+// 都没有时创建新用户。
+func (s *Service) LoginWithIdentity(ctx context.Context, provider string, claims *oidc.Claims, linkUserID uint, meta audit.Meta) (string, error) {
+	var identity model.UserIdentity
+	err := s.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	found := err == nil
+
+	var user *model.User
+	switch {
+	case found && linkUserID != 0 && identity.UserID != linkUserID:
+		return "", ErrIdentityLinked
+	case found:
+		if user, err = s.GetProfile(ctx, identity.UserID); err != nil {
+			return "", err
+		}
+	case linkUserID != 0:
+		if user, err = s.GetProfile(ctx, linkUserID); err != nil {
+			return "", err
+		}
+	default:
+		if user, err = s.findOrCreateForIdentity(ctx, claims); err != nil {
+			return "", err
+		}
+	}
+
+	if user.Status != 1 {
+		return "", ErrUserDisabled
+	}
+
+	if !found {
+		identity = model.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}
+		if err := s.db.WithContext(ctx).Create(&identity).Error; err != nil {
+			return "", err
+		}
+		meta.ActorID = user.ID
+		s.auditSvc.Record(ctx, meta, user.ID, audit.ActionIdentityLink, nil, identityState(&identity))
+	}
+
+	meta.ActorID = user.ID
+	s.auditSvc.Record(ctx, meta, user.ID, audit.ActionLoginSuccess, nil, map[string]interface{}{"method": "oidc", "provider": provider})
+	return s.issueToken(user)
+}
+
+// findOrCreateForIdentity 只接受经过提供方验证的邮箱，按邮箱关联已有账号或创建新账号
+func (s *Service) findOrCreateForIdentity(ctx context.Context, claims *oidc.Claims) (*model.User, error) {
+	if claims.Email == "" {
+		return nil, ErrEmailRequired
+	}
+	if !claims.EmailVerified {
+		return nil, ErrEmailUnverified
+	}
+
+	email := normalizeEmail(claims.Email)
+	var user model.User
+	err := s.db.WithContext(ctx).Where("lower(email) = ?", email).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 外部身份首次登录等同于注册，邀请码模式下只能关联已有账号
+	if err := s.CheckRegistration(claims.Email); err != nil {
+		return nil, err
+	}
+	if s.registration.Mode == RegistrationInvite {
+		return nil, ErrInviteRequired
+	}
+
+	// 外部账号没有本地密码，需要时可以通过找回密码设置
+	hash, err := bcrypt.GenerateFromPassword([]byte(randomHex(32)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user = model.User{
+		Username: s.availableUsername(ctx, claims),
+		Email:    email,
+		Password: string(hash),
+	}
+	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *Service) availableUsername(ctx context.Context, claims *oidc.Claims) string {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameInvalid.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	name := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		s.db.WithContext(ctx).Model(&model.User{}).Unscoped().Where("username = ?", name).Count(&count)
+		if count == 0 {
+			return name
+		}
+		name = base + "_" + randomHex(2)
+	}
+	return base + "_" + randomHex(4)
+}
+
+func (s *Service) ListIdentities(ctx context.Context, userID uint) ([]model.UserIdentity, error) {
+	var list []model.UserIdentity
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&list).Error
+	return list, err
+}
+
+func (s *Service) UnlinkIdentity(ctx context.Context, userID uint, provider string, meta audit.Meta) error {
+	var identity model.UserIdentity
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND provider = ?", userID, provider).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(&identity).Error; err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, meta, userID, audit.ActionIdentityUnlink, identityState(&identity), nil)
+	return nil
+}
+
+func identityState(i *model.UserIdentity) map[string]interface{} {
+	return map[string]interface{}{
+		"provider": i.Provider,
+		"subject":  i.Subject,
+		"email":    i.Email,
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
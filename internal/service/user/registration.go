@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+
+	"gorm.io/gorm"
+)
+
+// 注册模式
+const (
+	RegistrationOpen   = "open"
+	RegistrationClosed = "closed"
+	RegistrationInvite = "invite"
+	RegistrationDomain = "domain"
+)
+
+var (
+	ErrRegistrationClosed    = errors.New("registration is closed")
+	ErrInviteRequired        = errors.New("invite code required")
+	ErrInvalidInvite         = errors.New("invite code is invalid, expired or used up")
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed to register")
+	ErrInviteNotFound        = errors.New("invite code not found")
+)
+
+// Registration 控制谁可以创建账号，对密码注册和外部身份首次登录都生效
+type Registration struct {
+	Mode string
+	// domain 模式下允许的邮箱域名，不含 @，不匹配子域名
+	AllowedDomains []string
+}
+
+// CheckRegistration 检查邮箱是否可以注册，邀请码在 Register 中校验
+func (s *Service) CheckRegistration(email string) error {
+	switch s.registration.Mode {
+	case RegistrationClosed:
+		return ErrRegistrationClosed
+	}
+	return s.CheckEmailDomain(email)
+}
+
+// CheckEmailDomain 在 domain 模式下检查邮箱域名，注册和更换邮箱都需要通过
+func (s *Service) CheckEmailDomain(email string) error {
+	if s.registration.Mode != RegistrationDomain {
+		return nil
+	}
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	for _, d := range s.registration.AllowedDomains {
+		if strings.EqualFold(d, domain) {
+			return nil
+		}
+	}
+	return ErrEmailDomainNotAllowed
+}
+
+// RegistrationMode 返回当前注册模式，供前端决定是否显示邀请码输入框
+func (s *Service) RegistrationMode() string {
+	return s.registration.Mode
+}
+
+// consumeInvite 在事务中占用一次邀请码，次数用完、过期或已作废时返回 ErrInvalidInvite
+func consumeInvite(tx *gorm.DB, code string) (*model.InviteCode, error) {
+	now := time.Now()
+	res := tx.Model(&model.InviteCode{}).
+		Where("code = ? AND revoked_at IS NULL", code).
+		Where("max_uses = 0 OR uses < max_uses").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalidInvite
+	}
+	var invite model.InviteCode
+	if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// CreateInviteCode 生成邀请码，maxUses 为 0 表示不限次数，ttl 为 0 表示不过期
+func (s *Service) CreateInviteCode(ctx context.Context, adminID uint, maxUses int, ttl time.Duration, note string, meta audit.Meta) (*model.InviteCode, error) {
+	invite := &model.InviteCode{
+		Code:      strings.ToUpper(randomHex(8)),
+		MaxUses:   maxUses,
+		Note:      note,
+		CreatedBy: adminID,
+	}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		invite.ExpiresAt = &expires
+	}
+	if err := s.db.WithContext(ctx).Create(invite).Error; err != nil {
+		return nil, err
+	}
+	s.auditSvc.Record(ctx, meta, 0, audit.ActionInviteCreate, nil, invite)
+	return invite, nil
+}
+
+func (s *Service) ListInviteCodes(ctx context.Context) ([]model.InviteCode, error) {
+	var list []model.InviteCode
+	err := s.db.WithContext(ctx).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// RevokeInviteCode 作废邀请码，已经注册的账号不受影响
+func (s *Service) RevokeInviteCode(ctx context.Context, id uint, meta audit.Meta) error {
+	var invite model.InviteCode
+	if err := s.db.WithContext(ctx).First(&invite, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInviteNotFound
+		}
+		return err
+	}
+	if invite.RevokedAt != nil {
+		return nil
+	}
+	before := invite
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(&invite).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, meta, 0, audit.ActionInviteRevoke, before, invite)
+	return nil
+}
@@ -1,12 +1,21 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+	"vapiv/internal/service/notification"
 	"vapiv/pkg/captcha"
-	"vapiv/pkg/email"
+	emailpkg "vapiv/pkg/email"
+	"vapiv/pkg/notify"
+	"vapiv/pkg/token"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -15,17 +24,41 @@ import (
 
 type Service struct {
 	db         *gorm.DB
-	jwtSecret  string
+	keys       *token.KeySet
 	jwtExpire  int
-	emailSvc   *email.Service
+	notifySvc  *notification.Service
 	captchaSvc *captcha.Service
+	auditSvc   *audit.Service
+
+	registration Registration
 }
 
-func NewService(db *gorm.DB, jwtSecret string, jwtExpire int, emailSvc *email.Service, captchaSvc *captcha.Service) *Service {
-	return &Service{db: db, jwtSecret: jwtSecret, jwtExpire: jwtExpire, emailSvc: emailSvc, captchaSvc: captchaSvc}
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrUsernameTaken      = errors.New("username is already taken")
+)
+
+func NewService(db *gorm.DB, keys *token.KeySet, jwtExpire int, notifySvc *notification.Service, captchaSvc *captcha.Service, auditSvc *audit.Service, registration Registration) *Service {
+	return &Service{db: db, keys: keys, jwtExpire: jwtExpire, notifySvc: notifySvc, captchaSvc: captchaSvc, auditSvc: auditSvc, registration: registration}
 }
 
-func (s *Service) Register(username, email, password string) (*model.User, error) {
+// Register 按注册模式检查后创建账号，invite 模式下邀请码和账号在同一事务中写入
+func (s *Service) Register(ctx context.Context, username, email, password, locale, inviteCode string, meta audit.Meta) (*model.User, error) {
+	if err := s.CheckRegistration(email); err != nil {
+		return nil, err
+	}
+	if s.registration.Mode == RegistrationInvite && inviteCode == "" {
+		return nil, ErrInviteRequired
+	}
+	email = normalizeEmail(email)
+	if s.EmailExists(ctx, email) {
+		return nil, ErrEmailTaken
+	}
+	if s.usernameExists(ctx, username) {
+		return nil, ErrUsernameTaken
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -35,54 +68,192 @@ func (s *Service) Register(username, email, password string) (*model.User, error
 		Username: username,
 		Email:    email,
 		Password: string(hash),
+		Locale:   emailpkg.NormalizeLocale(locale),
 	}
 
-	if err := s.db.Create(user).Error; err != nil {
+	var invite *model.InviteCode
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if s.registration.Mode == RegistrationInvite {
+			var err error
+			if invite, err = consumeInvite(tx, strings.ToUpper(strings.TrimSpace(inviteCode))); err != nil {
+				return err
+			}
+		}
+		return tx.Create(user).Error
+	})
+	if err != nil {
 		return nil, err
 	}
+
+	after := map[string]interface{}{"username": user.Username, "email": user.Email}
+	if invite != nil {
+		after["invite_code_id"] = invite.ID
+	}
+	meta.ActorID = user.ID
+	s.auditSvc.Record(ctx, meta, user.ID, audit.ActionRegister, nil, after)
 	return user, nil
 }
 
-func (s *Service) Login(username, password string) (string, error) {
+// Login 支持用户名或邮箱登录，成功和失败都会写入审计日志。
+// 先按用户名查找，兼容禁止 @ 之前注册的用户名；找不到时按邮箱查找，邮箱不区分大小写
+func (s *Service) Login(ctx context.Context, account, password string, meta audit.Meta) (string, error) {
 	var user model.User
-	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
-		return "", errors.New("invalid credentials")
+	err := s.db.WithContext(ctx).Where("username = ?", account).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && strings.Contains(account, "@") {
+		err = s.db.WithContext(ctx).Where("lower(email) = lower(?)", account).First(&user).Error
+	}
+	if err != nil {
+		s.auditSvc.Record(ctx, meta, 0, audit.ActionLoginFailure, nil, map[string]interface{}{"account": account, "reason": "unknown account"})
+		return "", ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+		s.auditSvc.Record(ctx, meta, user.ID, audit.ActionLoginFailure, nil, map[string]interface{}{"account": account, "reason": "wrong password"})
+		return "", ErrInvalidCredentials
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	meta.ActorID = user.ID
+	s.auditSvc.Record(ctx, meta, user.ID, audit.ActionLoginSuccess, nil, map[string]interface{}{"method": "password"})
+	return s.issueToken(&user)
+}
+
+func (s *Service) issueToken(user *model.User) (string, error) {
+	return s.keys.Sign(jwt.MapClaims{
+		"sub":     strconv.FormatUint(uint64(user.ID), 10),
 		"user_id": user.ID,
 		"exp":     time.Now().Add(time.Hour * time.Duration(s.jwtExpire)).Unix(),
 	})
-
-	return token.SignedString([]byte(s.jwtSecret))
 }
 
-func (s *Service) SendCode(email, purpose string) error {
-	code, err := s.captchaSvc.Generate(email, purpose)
+// SendCode 验证码进入邮件队列后立即返回；locale 为空时使用该邮箱对应账号的语言
+func (s *Service) SendCode(ctx context.Context, email, purpose, locale string) error {
+	email = normalizeEmail(email)
+	code, err := s.captchaSvc.Generate(ctx, email, purpose)
 	if err != nil {
 		return err
 	}
-	return s.emailSvc.SendCode(email, code)
+	return s.notifySvc.SendTo(ctx, notify.ChannelEmail, email, locale, notification.CodeMessage(code))
 }
 
-func (s *Service) VerifyCode(email, purpose, code string) bool {
-	return s.captchaSvc.Verify(email, purpose, code)
+func (s *Service) SetLocale(ctx context.Context, userID uint, locale string) error {
+	return s.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("locale", emailpkg.NormalizeLocale(locale)).Error
 }
 
-func (s *Service) EmailExists(email string) bool {
+// NewChallenge 发送验证码前需要先完成的工作量证明题
+func (s *Service) NewChallenge(ctx context.Context, ip string) (*captcha.Challenge, error) {
+	return s.captchaSvc.NewChallenge(ctx, ip)
+}
+
+func (s *Service) VerifyChallenge(ctx context.Context, token string) error {
+	return s.captchaSvc.VerifyChallenge(ctx, token)
+}
+
+func (s *Service) VerifyCode(ctx context.Context, email, purpose, code string) bool {
+	return s.captchaSvc.Verify(ctx, normalizeEmail(email), purpose, code)
+}
+
+// normalizeEmail 邮箱统一按小写保存和比较
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailExists 不区分大小写，兼容统一小写之前保存的邮箱
+func (s *Service) EmailExists(ctx context.Context, email string) bool {
+	var count int64
+	s.db.WithContext(ctx).Model(&model.User{}).Where("lower(email) = ?", normalizeEmail(email)).Count(&count)
+	return count > 0
+}
+
+func (s *Service) usernameExists(ctx context.Context, username string) bool {
 	var count int64
-	s.db.Model(&model.User{}).Where("email = ?", email).Count(&count)
+	s.db.WithContext(ctx).Model(&model.User{}).Where("username = ?", username).Count(&count)
 	return count > 0
 }
 
-func (s *Service) ResetPassword(email, newPassword string) error {
+func (s *Service) ResetPassword(ctx context.Context, email, newPassword string, meta audit.Meta) error {
+	var user model.User
+	if err := s.db.WithContext(ctx).Where("lower(email) = ?", normalizeEmail(email)).First(&user).Error; err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(&user).Update("password", string(hash)).Error; err != nil {
+		return err
+	}
+
+	meta.ActorID = user.ID
+	s.auditSvc.Record(ctx, meta, user.ID, audit.ActionPasswordReset, nil, map[string]interface{}{"via": "email"})
+	s.notifySvc.NotifyAsync(ctx, user.ID, notification.PasswordChangedMessage())
+	return nil
+}
+
+// ChangePassword 需要提供当前密码
+func (s *Service) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string, meta audit.Meta) error {
+	if !s.CheckPassword(ctx, userID, oldPassword) {
+		return ErrInvalidCredentials
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
-	return s.db.Model(&model.User{}).Where("email = ?", email).Update("password", string(hash)).Error
+	if err := s.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("password", string(hash)).Error; err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, meta, userID, audit.ActionPasswordChange, nil, nil)
+	s.notifySvc.NotifyAsync(ctx, userID, notification.PasswordChangedMessage())
+	return nil
+}
+
+// 更换邮箱的验证码同时绑定用户，避免被其他账号使用
+func changeEmailPurpose(userID uint) string {
+	return fmt.Sprintf("change_email:%d", userID)
+}
+
+func (s *Service) SendChangeEmailCode(ctx context.Context, userID uint, newEmail string) error {
+	if err := s.CheckEmailDomain(newEmail); err != nil {
+		return err
+	}
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.SendCode(ctx, newEmail, changeEmailPurpose(userID), user.Locale)
+}
+
+func (s *Service) VerifyChangeEmailCode(ctx context.Context, userID uint, newEmail, code string) bool {
+	return s.VerifyCode(ctx, newEmail, changeEmailPurpose(userID), code)
+}
+
+// ChangeEmail 更换邮箱，调用前需要校验新邮箱的验证码。domain 模式下新邮箱同样受域名限制
+func (s *Service) ChangeEmail(ctx context.Context, userID uint, newEmail string, meta audit.Meta) error {
+	if err := s.CheckEmailDomain(newEmail); err != nil {
+		return err
+	}
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return err
+	}
+	newEmail = normalizeEmail(newEmail)
+	if s.EmailExists(ctx, newEmail) {
+		return ErrEmailTaken
+	}
+
+	oldEmail := user.Email
+	if err := s.db.WithContext(ctx).Model(&user).Update("email", newEmail).Error; err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, meta, userID, audit.ActionEmailChange, map[string]interface{}{"email": oldEmail}, map[string]interface{}{"email": newEmail})
+
+	// 旧邮箱不再属于账号，单独通知；其他渠道按用户偏好发送
+	msg := notification.EmailChangedMessage(newEmail)
+	if err := s.notifySvc.SendTo(ctx, notify.ChannelEmail, oldEmail, user.Locale, msg); err != nil {
+		slog.Error("notify old email failed", "user_id", userID, "error", err)
+	}
+	s.notifySvc.NotifyAsync(ctx, userID, msg)
+	return nil
 }
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vapiv/internal/model"
+	"vapiv/internal/service/audit"
+)
+
+func TestRegisterTaken(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestService(t, Registration{Mode: RegistrationOpen})
+	if _, err := s.Register(ctx, "alice", "alice@example.com", "password", "en", "", audit.Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	bob, err := s.Register(ctx, "bob", "bob@example.com", "password", "en", "", audit.Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Register(ctx, "carol", "alice@example.com", "password", "en", "", audit.Meta{}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("register with taken email: %v", err)
+	}
+	if _, err := s.Register(ctx, "alice", "carol@example.com", "password", "en", "", audit.Meta{}); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("register with taken username: %v", err)
+	}
+	if err := s.ChangeEmail(ctx, bob.ID, "alice@example.com", audit.Meta{}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("change to taken email: %v", err)
+	}
+}
+
+func TestEmailCaseInsensitive(t *testing.T) {
+	ctx := context.Background()
+	s, db, _ := newTestService(t, Registration{Mode: RegistrationOpen})
+	u, err := s.Register(ctx, "alice", "Alice@Example.com", "password", "en", "", audit.Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "alice@example.com" {
+		t.Errorf("stored email %q", u.Email)
+	}
+	if _, err := s.Register(ctx, "alice2", "ALICE@example.com", "password", "en", "", audit.Meta{}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("register with differently cased email: %v", err)
+	}
+
+	// 升级前保存的大小写混合的邮箱
+	legacy := model.User{Username: "bob", Email: "Bob@Example.com"}
+	db.Create(&legacy)
+	if !s.EmailExists(ctx, "bob@example.com") {
+		t.Error("legacy mixed-case email not found")
+	}
+	if err := s.ResetPassword(ctx, "BOB@example.com", "newpassword", audit.Meta{}); err != nil {
+		t.Errorf("reset password: %v", err)
+	}
+	if err := s.ChangeEmail(ctx, legacy.ID, "Bob2@Example.com", audit.Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&legacy, legacy.ID)
+	if legacy.Email != "bob2@example.com" {
+		t.Errorf("changed email %q", legacy.Email)
+	}
+}
@@ -21,25 +21,25 @@ func NewService(rdb *redis.Client) *Service {
 	return &Service{rdb: rdb, ttl: 5 * time.Minute}
 }
 
-func (s *Service) Generate(email, purpose string) (string, error) {
+func (s *Service) Generate(ctx context.Context, email, purpose string) (string, error) {
 	if s.rdb == nil {
 		return "", ErrRedisUnavailable
 	}
 	code := generateCode()
 	key := fmt.Sprintf("captcha:%s:%s", purpose, email)
-	return code, s.rdb.Set(context.Background(), key, code, s.ttl).Err()
+	return code, s.rdb.Set(ctx, key, code, s.ttl).Err()
 }
 
-func (s *Service) Verify(email, purpose, code string) bool {
+func (s *Service) Verify(ctx context.Context, email, purpose, code string) bool {
 	if s.rdb == nil {
 		return false
 	}
 	key := fmt.Sprintf("captcha:%s:%s", purpose, email)
-	stored, err := s.rdb.Get(context.Background(), key).Result()
+	stored, err := s.rdb.Get(ctx, key).Result()
 	if err != nil || stored != code {
 		return false
 	}
-	s.rdb.Del(context.Background(), key)
+	s.rdb.Del(ctx, key)
 	return true
 }
 
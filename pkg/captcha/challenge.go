@@ -0,0 +1,133 @@
+package captcha
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrChallengeFailed = errors.New("challenge not solved or expired")
+
+const (
+	challengeTTL = 5 * time.Minute
+	// 按 IP 统计最近一段时间领取的题目数量
+	volumeWindow = 10 * time.Minute
+
+	baseDifficulty = 16
+	maxDifficulty  = 26
+)
+
+// Challenge 是一道工作量证明题：找到 nonce 使 sha256(prefix + nonce) 至少有 Difficulty 个前导零比特。
+// 提交时使用 "<id>.<nonce>" 作为解答 token
+type Challenge struct {
+	ID         string `json:"id"`
+	Prefix     string `json:"prefix"`
+	Difficulty int    `json:"difficulty"`
+	Algorithm  string `json:"algorithm"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// NewChallenge 生成题目，同一 IP 最近领取的越多难度越高
+func (s *Service) NewChallenge(ctx context.Context, ip string) (*Challenge, error) {
+	if s.rdb == nil {
+		return nil, ErrRedisUnavailable
+	}
+
+	volumeKey := "challenge:ip:" + ip
+	count, err := s.rdb.Incr(ctx, volumeKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if count == 1 {
+		s.rdb.Expire(ctx, volumeKey, volumeWindow)
+	}
+
+	c := &Challenge{
+		ID:         randomHex(16),
+		Prefix:     randomHex(16),
+		Difficulty: difficulty(count),
+		Algorithm:  "sha256",
+		ExpiresIn:  int(challengeTTL.Seconds()),
+	}
+	value := c.Prefix + ":" + strconv.Itoa(c.Difficulty)
+	if err := s.rdb.Set(ctx, "challenge:"+c.ID, value, challengeTTL).Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// VerifyChallenge 校验解答 token，每道题只能使用一次
+func (s *Service) VerifyChallenge(ctx context.Context, token string) error {
+	if s.rdb == nil {
+		return ErrRedisUnavailable
+	}
+	id, nonce, ok := strings.Cut(token, ".")
+	if !ok || id == "" || nonce == "" || len(nonce) > 64 {
+		return ErrChallengeFailed
+	}
+
+	value, err := s.rdb.GetDel(ctx, "challenge:"+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrChallengeFailed
+	}
+	if err != nil {
+		return err
+	}
+
+	prefix, d, _ := strings.Cut(value, ":")
+	difficulty, _ := strconv.Atoi(d)
+	if leadingZeroBits(prefix+nonce) < difficulty {
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+// difficulty 前 5 次使用基础难度，之后请求量每翻一倍增加 2 比特（计算量约翻 4 倍）
+func difficulty(count int64) int {
+	d := baseDifficulty
+	for n := count / 5; n > 0; n /= 2 {
+		d += 2
+	}
+	if d > maxDifficulty {
+		d = maxDifficulty
+	}
+	return d
+}
+
+func leadingZeroBits(s string) int {
+	sum := sha256.Sum256([]byte(s))
+	n := 0
+	for _, b := range sum {
+		if b == 0 {
+			n += 8
+			continue
+		}
+		n += bits.LeadingZeros8(b)
+		break
+	}
+	return n
+}
+
+// Solve 暴力求解题目，供 Go 客户端和测试使用
+func Solve(prefix string, difficulty int) string {
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		if leadingZeroBits(prefix+nonce) >= difficulty {
+			return nonce
+		}
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewService(rdb)
+}
+
+func TestDifficulty(t *testing.T) {
+	cases := map[int64]int{
+		1:    baseDifficulty,
+		4:    baseDifficulty,
+		5:    baseDifficulty + 2,
+		9:    baseDifficulty + 2,
+		10:   baseDifficulty + 4,
+		20:   baseDifficulty + 6,
+		40:   baseDifficulty + 8,
+		1000: maxDifficulty,
+	}
+	for count, want := range cases {
+		if got := difficulty(count); got != want {
+			t.Errorf("difficulty(%d) = %d, want %d", count, got, want)
+		}
+	}
+	for n := int64(1); n < 1<<20; n *= 3 {
+		if difficulty(n) > difficulty(n*3) {
+			t.Errorf("difficulty decreases between %d and %d", n, n*3)
+		}
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	// sha256("abc") = ba7816bf...，第一个字节 0xba 没有前导零
+	if got := leadingZeroBits("abc"); got != 0 {
+		t.Errorf("leadingZeroBits(abc) = %d, want 0", got)
+	}
+	for _, d := range []int{1, 4, 8, 12} {
+		nonce := Solve("prefix", d)
+		if got := leadingZeroBits("prefix" + nonce); got < d {
+			t.Errorf("Solve(%d) returned %s with %d zero bits", d, nonce, got)
+		}
+	}
+}
+
+func TestVerifyChallengeIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	c, err := s.NewChallenge(ctx, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Difficulty != baseDifficulty {
+		t.Fatalf("difficulty = %d, want %d", c.Difficulty, baseDifficulty)
+	}
+
+	token := c.ID + "." + Solve(c.Prefix, c.Difficulty)
+	if err := s.VerifyChallenge(ctx, token); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.VerifyChallenge(ctx, token); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("second use: err = %v, want ErrChallengeFailed", err)
+	}
+}
+
+func TestVerifyChallengeRejectsWrongAnswer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	c, err := s.NewChallenge(ctx, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 找一个不满足难度的 nonce
+	nonce := "0"
+	for i := 0; leadingZeroBits(c.Prefix+nonce) >= c.Difficulty; i++ {
+		nonce = strings.Repeat("x", i+1)
+	}
+	for _, token := range []string{c.ID + "." + nonce, c.ID, "." + nonce, "unknown." + Solve(c.Prefix, c.Difficulty)} {
+		if err := s.VerifyChallenge(ctx, token); !errors.Is(err, ErrChallengeFailed) {
+			t.Errorf("VerifyChallenge(%q) = %v, want ErrChallengeFailed", token, err)
+		}
+	}
+	// 错误的解答也会消耗题目
+	if err := s.VerifyChallenge(ctx, c.ID+"."+Solve(c.Prefix, c.Difficulty)); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("challenge still usable after a wrong answer: %v", err)
+	}
+}
+
+func TestNewChallengeRaisesDifficultyPerIP(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	var last *Challenge
+	for i := 0; i < 10; i++ {
+		c, err := s.NewChallenge(ctx, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = c
+	}
+	if last.Difficulty <= baseDifficulty {
+		t.Errorf("difficulty after 10 challenges = %d", last.Difficulty)
+	}
+
+	other, err := s.NewChallenge(ctx, "192.0.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Difficulty != baseDifficulty {
+		t.Errorf("other IP difficulty = %d, want %d", other.Difficulty, baseDifficulty)
+	}
+}
+
+func TestChallengeWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(nil)
+	if _, err := s.NewChallenge(ctx, "192.0.2.1"); !errors.Is(err, ErrRedisUnavailable) {
+		t.Errorf("NewChallenge err = %v", err)
+	}
+	if err := s.VerifyChallenge(ctx, "a.b"); !errors.Is(err, ErrRedisUnavailable) {
+		t.Errorf("VerifyChallenge err = %v", err)
+	}
+}
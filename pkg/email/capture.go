@@ -0,0 +1,109 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSender 把完整的 MIME 邮件写成 .eml 文件，用于本地开发
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if dir == "" {
+		return nil, errors.New("email capture dir is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(m *Mail) error {
+	msg, err := buildMessage(s.from, m)
+	if err != nil {
+		return err
+	}
+	if m.ID == "" {
+		m.ID = newID()
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), m.ID)
+	return os.WriteFile(filepath.Join(s.dir, name), msg, 0o644)
+}
+
+// CapturedMail 是内存收件箱中的一封邮件
+type CapturedMail struct {
+	Mail
+	From       string    `json:"from"`
+	Raw        string    `json:"raw"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+const inboxLimit = 500
+
+// MemorySender 把邮件保存在内存中（最多 500 封），通过收件箱接口查看，用于集成测试
+type MemorySender struct {
+	from string
+
+	mu    sync.Mutex
+	inbox []CapturedMail
+}
+
+func NewMemorySender(from string) *MemorySender {
+	return &MemorySender{from: from}
+}
+
+func (s *MemorySender) Send(m *Mail) error {
+	msg, err := buildMessage(s.from, m)
+	if err != nil {
+		return err
+	}
+	if m.ID == "" {
+		m.ID = newID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inbox = append(s.inbox, CapturedMail{Mail: *m, From: s.from, Raw: string(msg), ReceivedAt: time.Now()})
+	if len(s.inbox) > inboxLimit {
+		s.inbox = s.inbox[len(s.inbox)-inboxLimit:]
+	}
+	return nil
+}
+
+// Messages 按时间倒序返回邮件，to 不为空时只返回发给该地址的邮件
+func (s *MemorySender) Messages(to string) []CapturedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]CapturedMail, 0, len(s.inbox))
+	for i := len(s.inbox) - 1; i >= 0; i-- {
+		if to == "" || s.inbox[i].To == to {
+			list = append(list, s.inbox[i])
+		}
+	}
+	return list
+}
+
+func (s *MemorySender) Get(id string) (CapturedMail, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.inbox {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return CapturedMail{}, false
+}
+
+func (s *MemorySender) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inbox = nil
+}
@@ -0,0 +1,80 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileSender(t *testing.T) {
+	if _, err := NewFileSender("", "noreply@example.com"); err == nil {
+		t.Error("empty dir accepted")
+	}
+
+	dir := filepath.Join(t.TempDir(), "mail")
+	s, err := NewFileSender(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Mail{To: "alice@example.com", Subject: "Welcome", Text: "hello"}
+	if err := s.Send(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID == "" {
+		t.Fatal("mail has no id")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*-"+m.ID+".eml"))
+	if len(files) != 1 {
+		t.Fatalf("files %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	for _, want := range []string{"To: alice@example.com", "Subject: Welcome", "hello"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("eml missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestMemorySender(t *testing.T) {
+	s := NewMemorySender("noreply@example.com")
+	for i, to := range []string{"alice@example.com", "bob@example.com", "alice@example.com"} {
+		if err := s.Send(&Mail{To: to, Subject: "mail " + strconv.Itoa(i), Text: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if all := s.Messages(""); len(all) != 3 {
+		t.Errorf("%d messages, want 3", len(all))
+	}
+	alice := s.Messages("alice@example.com")
+	if len(alice) != 2 || alice[0].Subject != "mail 2" || alice[1].Subject != "mail 0" {
+		t.Fatalf("alice's inbox is not newest first: %+v", alice)
+	}
+	if !strings.Contains(alice[0].Raw, "Subject: mail 2") || alice[0].From != "noreply@example.com" {
+		t.Errorf("captured mail %+v", alice[0])
+	}
+
+	if m, ok := s.Get(alice[1].ID); !ok || m.Subject != "mail 0" {
+		t.Errorf("Get(%s) = %+v, %v", alice[1].ID, m, ok)
+	}
+	if _, ok := s.Get("missing"); ok {
+		t.Error("Get of an unknown id succeeded")
+	}
+
+	s.Clear()
+	if n := len(s.Messages("")); n != 0 {
+		t.Errorf("%d messages after Clear", n)
+	}
+
+	// 超出上限时丢弃最早的邮件
+	for i := 0; i < inboxLimit+10; i++ {
+		s.Send(&Mail{To: "alice@example.com", Subject: strconv.Itoa(i), Text: "hello"})
+	}
+	list := s.Messages("")
+	if len(list) != inboxLimit || list[len(list)-1].Subject != "10" {
+		t.Errorf("%d messages, oldest %q", len(list), list[len(list)-1].Subject)
+	}
+}
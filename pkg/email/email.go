@@ -1,38 +1,150 @@
 package email
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"net/smtp"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
 )
 
-type Service struct {
-	host     string
-	port     int
-	username string
-	password string
-	from     string
-}
-
-func NewService(host string, port int, username, password, from string) *Service {
-	return &Service{
-		host:     host,
-		port:     port,
-		username: username,
-		password: password,
-		from:     from,
+// Sender 是邮件的实际投递方式：SMTP、写入文件或内存收件箱
+type Sender interface {
+	Send(m *Mail) error
+}
+
+// Mail 是一封待发送的邮件，HTML 为空时只发送纯文本
+type Mail struct {
+	ID       string `json:"id"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	HTML     string `json:"html,omitempty"`
+	Attempts int    `json:"attempts"`
+	// 包含验证码或邀请链接，多次发送失败后直接丢弃，不写入 mail:dead
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+const (
+	BackendSMTP   = "smtp"
+	BackendFile   = "file"
+	BackendMemory = "memory"
+)
+
+type Config struct {
+	// smtp（默认）、file 或 memory
+	Backend string
+	From    string
+
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// starttls（默认）、tls（465 隐式 TLS）或 none
+	Security string
+	// plain、login、cram-md5 或 none；为空时有用户名用 plain，否则不认证
+	Auth string
+	// 每个实例保持的空闲 SMTP 连接数
+	PoolSize int
+
+	// file 后端写入 .eml 的目录
+	CaptureDir string
+}
+
+// NewSender 按 Backend 创建 Sender
+func NewSender(cfg Config) (Sender, error) {
+	switch cfg.Backend {
+	case "", BackendSMTP:
+		return NewSMTPSender(cfg)
+	case BackendFile:
+		return NewFileSender(cfg.CaptureDir, cfg.From)
+	case BackendMemory:
+		return NewMemorySender(cfg.From), nil
 	}
+	return nil, fmt.Errorf("unknown email backend %q", cfg.Backend)
 }
 
-func (s *Service) SendCode(to, code string) error {
-	subject := "VAPIV 验证码"
-	body := fmt.Sprintf("您的验证码是: %s\n\n验证码5分钟内有效，请勿泄露给他人。", code)
-	return s.send(to, subject, body)
+// buildMessage 生成 MIME 邮件，有 HTML 时使用 multipart/alternative
+func buildMessage(from string, m *Mail) ([]byte, error) {
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", m.To)
+	header.Set("Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(from))
+	header.Set("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header.Set("Content-Type", "text/plain; charset=UTF-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQP(&buf, m.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", m.Text},
+		{"text/html; charset=UTF-8", m.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	writeHeader(&buf, header)
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, k := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if v := header.Get(k); v != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQP(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+	return "<" + newID() + "@" + domain + ">"
 }
 
-func (s *Service) send(to, subject, body string) error {
-	auth := smtp.PlainAuth("", s.username, s.password, s.host)
-	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
-		s.from, to, subject, body)
-	addr := fmt.Sprintf("%s:%d", s.host, s.port)
-	return smtp.SendMail(addr, auth, s.from, []string{to}, []byte(msg))
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
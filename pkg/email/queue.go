@@ -0,0 +1,230 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	queueKey = "mail:queue"
+	retryKey = "mail:retry"
+	deadKey  = "mail:dead"
+	// 每个消费者有自己的 processing 列表和心跳 key，consumersKey 记录所有消费者
+	processingPrefix = "mail:processing:"
+	heartbeatPrefix  = "mail:consumer:"
+	consumersKey     = "mail:consumers"
+	// 旧版本所有实例共用的 processing 列表
+	legacyProcessingKey = "mail:processing"
+
+	// 心跳超过这个时间没有刷新的消费者视为已退出，它的 processing 列表会被放回队列
+	consumerTTL = 30 * time.Second
+	// mail:dead 最后一次写入后保留的时间
+	deadTTL = 7 * 24 * time.Hour
+)
+
+// Queue 把邮件放入 Redis 列表由后台发送，失败后按指数退避重试，
+// 超过最大次数后移入 mail:dead（敏感邮件直接丢弃）。没有 Redis 时在后台 goroutine 中直接发送一次
+type Queue struct {
+	rdb         *redis.Client
+	sender      Sender
+	maxAttempts int
+	backoff     time.Duration
+	// 消费者 ID，每个实例不同
+	consumer string
+	// 没有 Redis 时正在发送的邮件
+	sending sync.WaitGroup
+}
+
+func NewQueue(rdb *redis.Client, sender Sender) *Queue {
+	return &Queue{rdb: rdb, sender: sender, maxAttempts: 5, backoff: 30 * time.Second, consumer: newID()}
+}
+
+// Enqueue 只写入队列，不等待发送结果
+func (q *Queue) Enqueue(ctx context.Context, m *Mail) error {
+	if m.ID == "" {
+		m.ID = newID()
+	}
+	if q.rdb == nil {
+		q.sending.Add(1)
+		go func() {
+			defer q.sending.Done()
+			if err := q.sender.Send(m); err != nil {
+				slog.Error("send mail failed", "mail_id", m.ID, "to", m.To, "error", err)
+			}
+		}()
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return q.rdb.LPush(ctx, queueKey, data).Err()
+}
+
+// Run 消费队列直到 ctx 结束，ctx 结束时正在发送的邮件会发送完再返回。
+// 取出的邮件放在本实例的 processing 列表中，只有心跳过期（实例已退出）后才会被其他实例放回队列；
+// 实例在发送成功后、移出 processing 前崩溃时，同一封邮件仍可能被发送两次
+func (q *Queue) Run(ctx context.Context) {
+	if q.rdb == nil {
+		return
+	}
+
+	processingKey := processingPrefix + q.consumer
+	q.heartbeat(ctx)
+	q.rdb.SAdd(ctx, consumersKey, q.consumer)
+	defer func() {
+		bg := context.WithoutCancel(ctx)
+		q.rdb.Del(bg, heartbeatPrefix+q.consumer)
+		q.rdb.SRem(bg, consumersKey, q.consumer)
+	}()
+	q.reclaim(ctx)
+	// 升级前所有实例共用的列表，只在启动时回收一次；滚动升级期间可能造成少量重复发送
+	q.drain(ctx, legacyProcessingKey)
+	go q.promote(ctx)
+
+	for ctx.Err() == nil {
+		data, err := q.rdb.BLMove(ctx, queueKey, processingKey, "RIGHT", "LEFT", 5*time.Second).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				slog.Error("read mail queue failed", "error", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// 已经取出的邮件不受 ctx 取消影响，避免发送成功后留在 processing 中
+		q.process(context.WithoutCancel(ctx), data)
+		q.rdb.LRem(context.WithoutCancel(ctx), processingKey, 1, data)
+	}
+}
+
+// Close 等待没有 Redis 时在后台发送的邮件，然后关闭发送方的连接。
+// 应在 Run 返回、不再有新邮件写入后调用
+func (q *Queue) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		q.sending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if c, ok := q.sender.(interface{ Close() }); ok {
+		c.Close()
+	}
+	return nil
+}
+
+func (q *Queue) process(ctx context.Context, data string) {
+	var m Mail
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		slog.Warn("drop invalid mail queue message", "error", err)
+		return
+	}
+
+	err := q.sender.Send(&m)
+	if err == nil {
+		return
+	}
+
+	m.Attempts++
+	payload, _ := json.Marshal(&m)
+	if m.Attempts >= q.maxAttempts {
+		slog.Error("send mail failed, giving up", "mail_id", m.ID, "to", m.To, "attempts", m.Attempts, "error", err)
+		if m.Sensitive {
+			return
+		}
+		q.rdb.LPush(ctx, deadKey, payload)
+		q.rdb.LTrim(ctx, deadKey, 0, 999)
+		q.rdb.Expire(ctx, deadKey, deadTTL)
+		return
+	}
+
+	delay := q.backoff << (m.Attempts - 1)
+	slog.Warn("send mail failed, will retry", "mail_id", m.ID, "to", m.To, "attempt", m.Attempts, "retry_in", delay, "error", err)
+	q.rdb.ZAdd(ctx, retryKey, redis.Z{
+		Score:  float64(time.Now().Add(delay).Unix()),
+		Member: payload,
+	})
+}
+
+// heartbeat 刷新本实例的心跳，心跳存在时其他实例不会回收本实例的 processing 列表
+func (q *Queue) heartbeat(ctx context.Context) {
+	q.rdb.Set(ctx, heartbeatPrefix+q.consumer, time.Now().Unix(), consumerTTL)
+}
+
+// reclaim 把已退出的消费者未处理完的邮件放回队列
+func (q *Queue) reclaim(ctx context.Context) {
+	consumers, err := q.rdb.SMembers(ctx, consumersKey).Result()
+	if err != nil {
+		return
+	}
+	for _, id := range consumers {
+		if id == q.consumer {
+			continue
+		}
+		if n, err := q.rdb.Exists(ctx, heartbeatPrefix+id).Result(); err != nil || n > 0 {
+			continue
+		}
+		// LMove 逐条原子移动，多个实例同时回收时每封邮件只会被放回一次
+		if q.drain(ctx, processingPrefix+id) == nil {
+			q.rdb.SRem(ctx, consumersKey, id)
+		}
+	}
+
+}
+
+func (q *Queue) drain(ctx context.Context, key string) error {
+	for {
+		err := q.rdb.LMove(ctx, key, queueKey, "RIGHT", "LEFT").Err()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// promote 定期刷新心跳、回收已退出实例的邮件，并把到期的重试邮件放回队列
+func (q *Queue) promote(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		q.heartbeat(ctx)
+		q.reclaim(ctx)
+
+		due, err := q.rdb.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(time.Now().Unix(), 10),
+			Count: 100,
+		}).Result()
+		if err != nil {
+			continue
+		}
+		for _, data := range due {
+			// 多个实例同时执行时只有 ZRem 成功的一方放回队列
+			if n, _ := q.rdb.ZRem(ctx, retryKey, data).Result(); n == 1 {
+				q.rdb.LPush(ctx, queueKey, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type failingSender struct{}
+
+func (failingSender) Send(*Mail) error { return errors.New("smtp down") }
+
+func newTestQueue(t *testing.T, mr *miniredis.Miniredis) *Queue {
+	t.Helper()
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewQueue(rdb, failingSender{})
+}
+
+func TestReclaimSkipsLiveConsumers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+	live, dead, self := newTestQueue(t, mr), newTestQueue(t, mr), newTestQueue(t, mr)
+
+	for _, q := range []*Queue{live, dead} {
+		q.heartbeat(ctx)
+		q.rdb.SAdd(ctx, consumersKey, q.consumer)
+		q.rdb.LPush(ctx, processingPrefix+q.consumer, `{"id":"`+q.consumer+`"}`)
+	}
+	mr.Del(heartbeatPrefix + dead.consumer)
+
+	self.reclaim(ctx)
+
+	if !mr.Exists(processingPrefix + live.consumer) {
+		t.Error("processing list of a live consumer was reclaimed")
+	}
+	queued, _ := mr.List(queueKey)
+	if len(queued) != 1 || queued[0] != `{"id":"`+dead.consumer+`"}` {
+		t.Errorf("queue = %v, want the dead consumer's mail", queued)
+	}
+	if ok, _ := mr.SIsMember(consumersKey, dead.consumer); ok {
+		t.Error("dead consumer still registered")
+	}
+
+	// 心跳过期后同样会被回收
+	mr.FastForward(consumerTTL + time.Second)
+	self.reclaim(ctx)
+	if queued, _ := mr.List(queueKey); len(queued) != 2 {
+		t.Errorf("queue after heartbeat expiry = %v", queued)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+	q := newTestQueue(t, mr)
+
+	last := q.maxAttempts - 1
+	notice, _ := json.Marshal(&Mail{ID: "notice", To: "a@example.com", Attempts: last})
+	code, _ := json.Marshal(&Mail{ID: "code", To: "a@example.com", Attempts: last, Sensitive: true})
+	q.process(ctx, string(notice))
+	q.process(ctx, string(code))
+
+	dead, _ := mr.List(deadKey)
+	if len(dead) != 1 {
+		t.Fatalf("dead = %v, want only the notice", dead)
+	}
+	var m Mail
+	json.Unmarshal([]byte(dead[0]), &m)
+	if m.ID != "notice" {
+		t.Errorf("dead letter = %s, want notice", m.ID)
+	}
+	if ttl := mr.TTL(deadKey); ttl <= 0 || ttl > deadTTL {
+		t.Errorf("dead list TTL = %s", ttl)
+	}
+}
+
+func TestRetryBeforeMaxAttempts(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q := newTestQueue(t, mr)
+
+	data, _ := json.Marshal(&Mail{ID: "m1", To: "a@example.com", Sensitive: true})
+	q.process(context.Background(), string(data))
+
+	retry, _ := mr.ZMembers(retryKey)
+	if len(retry) != 1 {
+		t.Fatalf("retry = %v", retry)
+	}
+	var m Mail
+	json.Unmarshal([]byte(retry[0]), &m)
+	if m.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", m.Attempts)
+	}
+}
@@ -0,0 +1,247 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+	SecurityNone     = "none"
+
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+	AuthNone    = "none"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	// 空闲超过该时间的连接不再复用，大多数服务器会在几分钟后断开空闲连接
+	maxIdle = 30 * time.Second
+)
+
+type pooledConn struct {
+	client *smtp.Client
+	idle   time.Time
+}
+
+// SMTPSender 支持 STARTTLS、隐式 TLS 和明文连接，以及 PLAIN/LOGIN/CRAM-MD5 认证，
+// 发送完成后把连接放回连接池复用
+type SMTPSender struct {
+	cfg  Config
+	addr string
+	from string
+	auth smtp.Auth
+	pool chan *pooledConn
+}
+
+func NewSMTPSender(cfg Config) (*SMTPSender, error) {
+	if cfg.Security == "" {
+		cfg.Security = SecurityStartTLS
+	}
+	if cfg.Auth == "" {
+		cfg.Auth = AuthNone
+		if cfg.Username != "" {
+			cfg.Auth = AuthPlain
+		}
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 2
+	}
+
+	s := &SMTPSender{
+		cfg:  cfg,
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from: cfg.From,
+		pool: make(chan *pooledConn, cfg.PoolSize),
+	}
+
+	switch cfg.Security {
+	case SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp security %q", cfg.Security)
+	}
+
+	switch cfg.Auth {
+	case AuthPlain:
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	case AuthLogin:
+		s.auth = LoginAuth(cfg.Username, cfg.Password, cfg.Host)
+	case AuthCRAMMD5:
+		s.auth = smtp.CRAMMD5Auth(cfg.Username, cfg.Password)
+	case AuthNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp auth %q", cfg.Auth)
+	}
+	return s, nil
+}
+
+func (s *SMTPSender) Send(m *Mail) error {
+	msg, err := buildMessage(s.from, m)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.get()
+	if err != nil {
+		return err
+	}
+	if err := s.deliver(c, m.To, msg); err != nil {
+		// 出错的连接状态未知，直接丢弃
+		c.client.Close()
+		return err
+	}
+	s.put(c)
+	return nil
+}
+
+func (s *SMTPSender) deliver(c *pooledConn, to string, msg []byte) error {
+	from := s.from
+	if addr, err := mail.ParseAddress(s.from); err == nil {
+		from = addr.Address
+	}
+
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+	if err := c.client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// get 优先复用空闲连接，连接已失效时重新建立
+func (s *SMTPSender) get() (*pooledConn, error) {
+	for {
+		select {
+		case c := <-s.pool:
+			if time.Since(c.idle) < maxIdle && c.client.Reset() == nil {
+				return c, nil
+			}
+			c.client.Close()
+		default:
+			client, err := s.dial()
+			if err != nil {
+				return nil, err
+			}
+			return &pooledConn{client: client}, nil
+		}
+	}
+}
+
+func (s *SMTPSender) put(c *pooledConn) {
+	c.idle = time.Now()
+	select {
+	case s.pool <- c:
+	default:
+		c.client.Quit()
+	}
+}
+
+func (s *SMTPSender) dial() (*smtp.Client, error) {
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.Security == SecurityTLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", s.addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", s.addr, dialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.cfg.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	if s.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			client.Close()
+			return nil, errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(s.auth); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Close 关闭所有空闲连接
+func (s *SMTPSender) Close() {
+	for {
+		select {
+		case c := <-s.pool:
+			c.client.Quit()
+		default:
+			return
+		}
+	}
+}
+
+type loginAuth struct {
+	username, password, host string
+}
+
+// LoginAuth 实现 AUTH LOGIN，和 smtp.PlainAuth 一样只在 TLS 或本机连接上发送密码
+func LoginAuth(username, password, host string) smtp.Auth {
+	return &loginAuth{username: username, password: password, host: host}
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,286 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP 是一个只支持明文连接的 SMTP 服务器，记录收到的认证信息和邮件
+type fakeSMTP struct {
+	ln       net.Listener
+	username string
+	password string
+
+	mu sync.Mutex
+	// 拒绝发给该地址的邮件
+	rejectRcpt string
+	conns      []net.Conn
+	accepted   int
+	logins     []string
+	messages   []string
+}
+
+func newFakeSMTP(t *testing.T, username, password string) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, username: username, password: password}
+	t.Cleanup(func() {
+		ln.Close()
+		s.drop()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.accepted++
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// drop 从服务器一端断开所有连接，模拟服务器关闭空闲连接
+func (s *fakeSMTP) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeSMTP) stats() (accepted int, logins, messages []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted, append([]string(nil), s.logins...), append([]string(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	readLine := func() (string, bool) {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err == nil
+	}
+	decode := func(s string) string {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return string(b)
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, ok := readLine()
+		if !ok {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-localhost")
+			reply("250 AUTH LOGIN PLAIN")
+		case cmd == "AUTH LOGIN":
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+			user, _ := readLine()
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+			pass, _ := readLine()
+			s.login(reply, decode(user), decode(pass))
+		case strings.HasPrefix(cmd, "AUTH PLAIN "):
+			parts := strings.Split(decode(line[len("AUTH PLAIN "):]), "\x00")
+			if len(parts) != 3 {
+				reply("501 malformed")
+				continue
+			}
+			s.login(reply, parts[1], parts[2])
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			reject := s.rejectRcpt != "" && strings.Contains(line, s.rejectRcpt)
+			s.mu.Unlock()
+			if reject {
+				reply("550 no such user")
+				continue
+			}
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, ok := readLine()
+				if !ok {
+					return
+				}
+				if l == "." {
+					break
+				}
+				data.WriteString(l + "\n")
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) login(reply func(string), user, pass string) {
+	if user != s.username || pass != s.password {
+		reply("535 authentication failed")
+		return
+	}
+	s.mu.Lock()
+	s.logins = append(s.logins, user)
+	s.mu.Unlock()
+	reply("235 authenticated")
+}
+
+func newTestSMTPSender(t *testing.T, srv *fakeSMTP, auth, password string) *SMTPSender {
+	t.Helper()
+	s, err := NewSMTPSender(Config{
+		Host:     "127.0.0.1",
+		Port:     srv.port(),
+		Username: srv.username,
+		Password: password,
+		From:     "VAPIV <noreply@example.com>",
+		Security: SecurityNone,
+		Auth:     auth,
+		PoolSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestSMTPAuth(t *testing.T) {
+	for _, auth := range []string{AuthLogin, AuthPlain} {
+		t.Run(auth, func(t *testing.T) {
+			srv := newFakeSMTP(t, "mailer", "s3cret")
+
+			if err := newTestSMTPSender(t, srv, auth, "wrong").Send(&Mail{To: "alice@example.com", Subject: "hi", Text: "hello"}); err == nil {
+				t.Error("send with a wrong password succeeded")
+			}
+
+			s := newTestSMTPSender(t, srv, auth, "s3cret")
+			if err := s.Send(&Mail{To: "alice@example.com", Subject: "hi", Text: "hello"}); err != nil {
+				t.Fatal(err)
+			}
+			_, logins, messages := srv.stats()
+			if len(logins) != 1 || logins[0] != "mailer" {
+				t.Errorf("logins %v", logins)
+			}
+			if len(messages) != 1 || !strings.Contains(messages[0], "To: alice@example.com") {
+				t.Errorf("messages %q", messages)
+			}
+		})
+	}
+}
+
+func TestSMTPPoolReusesConnection(t *testing.T) {
+	srv := newFakeSMTP(t, "mailer", "s3cret")
+	s := newTestSMTPSender(t, srv, AuthLogin, "s3cret")
+
+	for i := 0; i < 3; i++ {
+		if err := s.Send(&Mail{To: "alice@example.com", Subject: "hi " + strconv.Itoa(i), Text: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	accepted, logins, messages := srv.stats()
+	if accepted != 1 || len(logins) != 1 {
+		t.Errorf("%d connections and %d logins, want 1", accepted, len(logins))
+	}
+	if len(messages) != 3 {
+		t.Errorf("%d messages, want 3", len(messages))
+	}
+}
+
+func TestSMTPEvictsBrokenConnection(t *testing.T) {
+	srv := newFakeSMTP(t, "mailer", "s3cret")
+	s := newTestSMTPSender(t, srv, AuthLogin, "s3cret")
+	send := func(to string) error {
+		return s.Send(&Mail{To: to, Subject: "hi", Text: "hello"})
+	}
+
+	if err := send("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	// 服务器断开空闲连接后，下一次发送重新连接
+	srv.drop()
+	if err := send("alice@example.com"); err != nil {
+		t.Fatalf("send after the server dropped the connection: %v", err)
+	}
+	if accepted, _, _ := srv.stats(); accepted != 2 {
+		t.Errorf("%d connections, want 2", accepted)
+	}
+
+	// 发送出错的连接不放回连接池
+	srv.mu.Lock()
+	srv.rejectRcpt = "bounce@example.com"
+	srv.mu.Unlock()
+	if err := send("bounce@example.com"); err == nil {
+		t.Fatal("send to a rejected recipient succeeded")
+	}
+	if err := send("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if accepted, _, messages := srv.stats(); accepted != 3 || len(messages) != 3 {
+		t.Errorf("%d connections and %d messages, want 3 and 3", accepted, len(messages))
+	}
+}
+
+func TestLoginAuthStart(t *testing.T) {
+	tests := []struct {
+		host   string
+		server smtp.ServerInfo
+		ok     bool
+	}{
+		{"smtp.example.com", smtp.ServerInfo{Name: "smtp.example.com", TLS: true}, true},
+		{"localhost", smtp.ServerInfo{Name: "localhost"}, true},
+		{"smtp.example.com", smtp.ServerInfo{Name: "smtp.example.com"}, false},
+		{"smtp.example.com", smtp.ServerInfo{Name: "evil.example.com", TLS: true}, false},
+	}
+	for _, tt := range tests {
+		a := LoginAuth("user", "pass", tt.host)
+		_, _, err := a.Start(&tt.server)
+		if (err == nil) != tt.ok {
+			t.Errorf("Start(%+v) with host %s: %v", tt.server, tt.host, err)
+		}
+	}
+
+	a := LoginAuth("user", "pass", "localhost")
+	for challenge, want := range map[string]string{"Username:": "user", "password:": "pass"} {
+		if got, err := a.Next([]byte(challenge), true); err != nil || string(got) != want {
+			t.Errorf("Next(%q) = %q, %v", challenge, got, err)
+		}
+	}
+	if _, err := a.Next([]byte("Token:"), true); err == nil {
+		t.Error("unexpected challenge accepted")
+	}
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+const DefaultLocale = "zh-CN"
+
+var Locales = []string{"zh-CN", "en"}
+
+//go:embed all:templates
+var templateFS embed.FS
+
+type localized struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates[locale][name]，启动时全部解析，模板错误会直接 panic
+var templates = loadTemplates()
+
+func loadTemplates() map[string]map[string]*localized {
+	all := map[string]map[string]*localized{}
+	for _, locale := range Locales {
+		dir := path.Join("templates", locale)
+		entries, err := fs.ReadDir(templateFS, dir)
+		if err != nil {
+			panic(err)
+		}
+
+		all[locale] = map[string]*localized{}
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), ".tmpl")
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+			file := path.Join(dir, e.Name())
+			all[locale][name] = &localized{
+				text: texttemplate.Must(texttemplate.ParseFS(templateFS, file)),
+				html: htmltemplate.Must(htmltemplate.ParseFS(templateFS,
+					"templates/layout.tmpl", path.Join(dir, "_footer.tmpl"), file)),
+			}
+		}
+	}
+	return all
+}
+
+// Rendered 是渲染后的标题、纯文本正文和 HTML 正文
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Render 按语言渲染模板，不支持的语言使用默认语言
+func Render(name, locale string, data interface{}) (*Rendered, error) {
+	set, ok := templates[NormalizeLocale(locale)][name]
+	if !ok {
+		return nil, fmt.Errorf("email template %q not found", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := set.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if err := set.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, err
+	}
+	return &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    html.String(),
+	}, nil
+}
+
+// NormalizeLocale 把 zh、zh-cn、en-US 等归一到支持的语言
+func NormalizeLocale(locale string) string {
+	if l := MatchLocale(locale); l != "" {
+		return l
+	}
+	return DefaultLocale
+}
+
+// MatchLocale 从 Accept-Language 或单个语言标签中选出第一个支持的语言，没有匹配时返回空
+func MatchLocale(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(tag)
+		switch {
+		case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+			return "zh-CN"
+		case tag == "en" || strings.HasPrefix(tag, "en-"):
+			return "en"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package email
+
+import "testing"
+
+// 每个邮件模板都必须提供所有语言，并定义 subject、text、html 三部分
+func TestTemplatesCoverAllLocales(t *testing.T) {
+	names := map[string]bool{}
+	for _, set := range templates {
+		for name := range set {
+			names[name] = true
+		}
+	}
+	for _, locale := range Locales {
+		for name := range names {
+			tmpl, ok := templates[locale][name]
+			if !ok {
+				t.Errorf("templates/%s: missing %s.tmpl", locale, name)
+				continue
+			}
+			for _, part := range []string{"subject", "text"} {
+				if tmpl.text.Lookup(part) == nil {
+					t.Errorf("templates/%s/%s.tmpl: missing %q", locale, name, part)
+				}
+			}
+			if tmpl.html.Lookup("html") == nil {
+				t.Errorf("templates/%s/%s.tmpl: missing \"html\"", locale, name)
+			}
+		}
+	}
+}
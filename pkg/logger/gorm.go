@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Gorm 把 GORM 的日志写入 slog：超过 slow 的查询记为 warn，出错的查询记为 error，
+// level 为 info 时记录全部 SQL。记录不存在不算错误
+type Gorm struct {
+	log   *slog.Logger
+	level gormlogger.LogLevel
+	slow  time.Duration
+}
+
+func NewGorm(log *slog.Logger, level gormlogger.LogLevel, slow time.Duration) *Gorm {
+	return &Gorm{log: log, level: level, slow: slow}
+}
+
+// ParseGormLevel 解析 silent、error、warn、info
+func ParseGormLevel(s string) (gormlogger.LogLevel, error) {
+	switch s {
+	case "silent":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "", "warn":
+		return gormlogger.Warn, nil
+	case "info":
+		return gormlogger.Info, nil
+	}
+	return 0, errors.New("invalid sql log level " + s)
+}
+
+func (g *Gorm) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *g
+	copied.level = level
+	return &copied
+}
+
+func (g *Gorm) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.log.InfoContext(ctx, "gorm", attrs(ctx, slog.Any("args", args), slog.String("detail", msg))...)
+	}
+}
+
+func (g *Gorm) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.log.WarnContext(ctx, "gorm", attrs(ctx, slog.Any("args", args), slog.String("detail", msg))...)
+	}
+}
+
+func (g *Gorm) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.log.ErrorContext(ctx, "gorm", attrs(ctx, slog.Any("args", args), slog.String("detail", msg))...)
+	}
+}
+
+func (g *Gorm) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= gormlogger.Error:
+		sql, rows := fc()
+		g.log.ErrorContext(ctx, "sql error", attrs(ctx, sqlAttrs(sql, rows, elapsed), slog.String("error", err.Error()))...)
+	case g.slow > 0 && elapsed > g.slow && g.level >= gormlogger.Warn:
+		sql, rows := fc()
+		g.log.WarnContext(ctx, "slow sql", attrs(ctx, sqlAttrs(sql, rows, elapsed), slog.Duration("threshold", g.slow))...)
+	case g.level >= gormlogger.Info:
+		sql, rows := fc()
+		g.log.InfoContext(ctx, "sql", attrs(ctx, sqlAttrs(sql, rows, elapsed))...)
+	}
+}
+
+func sqlAttrs(sql string, rows int64, elapsed time.Duration) slog.Attr {
+	return slog.Group("sql",
+		slog.String("query", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("ms", float64(elapsed.Microseconds())/1000),
+	)
+}
+
+func attrs(ctx context.Context, list ...slog.Attr) []interface{} {
+	out := make([]interface{}, 0, len(list)+1)
+	if id := RequestID(ctx); id != "" {
+		out = append(out, slog.String("request_id", id))
+	}
+	for _, a := range list {
+		out = append(out, a)
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 群机器人只允许官方 webhook 地址，避免把服务器当作任意地址的请求代理
+var botHosts = map[string][]string{
+	ChannelDingTalk: {"oapi.dingtalk.com"},
+	ChannelWeCom:    {"qyapi.weixin.qq.com"},
+	ChannelFeishu:   {"open.feishu.cn", "open.larksuite.com"},
+}
+
+func validateBot(channel string, to Target) error {
+	u, err := url.Parse(to.Address)
+	if err != nil || u.Scheme != "https" {
+		return ErrInvalidTarget
+	}
+	for _, host := range botHosts[channel] {
+		if u.Host == host {
+			return nil
+		}
+	}
+	return ErrInvalidTarget
+}
+
+func botText(msg Message) string {
+	return msg.Subject + "\n\n" + msg.Body
+}
+
+// 三家机器人都在 HTTP 200 的响应体里返回错误码
+type botResult struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+func checkBotResult(channel string, body []byte) error {
+	var r botResult
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("%s: %w", channel, err)
+	}
+	if r.ErrCode != 0 {
+		return fmt.Errorf("%s: %d %s", channel, r.ErrCode, r.ErrMsg)
+	}
+	if r.Code != 0 {
+		return fmt.Errorf("%s: %d %s", channel, r.Code, r.Msg)
+	}
+	return nil
+}
+
+// DingTalkChannel 钉钉群机器人，Secret 对应“加签”密钥
+type DingTalkChannel struct {
+	client *http.Client
+}
+
+func NewDingTalkChannel(client *http.Client) *DingTalkChannel {
+	if client == nil {
+		client = defaultClient()
+	}
+	return &DingTalkChannel{client: client}
+}
+
+func (c *DingTalkChannel) Name() string {
+	return ChannelDingTalk
+}
+
+func (c *DingTalkChannel) Validate(to Target) error {
+	return validateBot(ChannelDingTalk, to)
+}
+
+func (c *DingTalkChannel) Send(ctx context.Context, to Target, msg Message) error {
+	addr := to.Address
+	if to.Secret != "" {
+		ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		m := hmac.New(sha256.New, []byte(to.Secret))
+		m.Write([]byte(ts + "\n" + to.Secret))
+		sign := base64.StdEncoding.EncodeToString(m.Sum(nil))
+		sep := "?"
+		if strings.Contains(addr, "?") {
+			sep = "&"
+		}
+		addr += sep + "timestamp=" + ts + "&sign=" + url.QueryEscape(sign)
+	}
+
+	payload := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": botText(msg)},
+	}
+	body, err := postJSON(ctx, c.client, addr, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkBotResult(ChannelDingTalk, body)
+}
+
+// WeComChannel 企业微信群机器人，地址中的 key 即为凭证
+type WeComChannel struct {
+	client *http.Client
+}
+
+func NewWeComChannel(client *http.Client) *WeComChannel {
+	if client == nil {
+		client = defaultClient()
+	}
+	return &WeComChannel{client: client}
+}
+
+func (c *WeComChannel) Name() string {
+	return ChannelWeCom
+}
+
+func (c *WeComChannel) Validate(to Target) error {
+	return validateBot(ChannelWeCom, to)
+}
+
+func (c *WeComChannel) Send(ctx context.Context, to Target, msg Message) error {
+	payload := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": botText(msg)},
+	}
+	body, err := postJSON(ctx, c.client, to.Address, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkBotResult(ChannelWeCom, body)
+}
+
+// FeishuChannel 飞书群机器人，Secret 对应“签名校验”密钥
+type FeishuChannel struct {
+	client *http.Client
+}
+
+func NewFeishuChannel(client *http.Client) *FeishuChannel {
+	if client == nil {
+		client = defaultClient()
+	}
+	return &FeishuChannel{client: client}
+}
+
+func (c *FeishuChannel) Name() string {
+	return ChannelFeishu
+}
+
+func (c *FeishuChannel) Validate(to Target) error {
+	return validateBot(ChannelFeishu, to)
+}
+
+func (c *FeishuChannel) Send(ctx context.Context, to Target, msg Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": botText(msg)},
+	}
+	if to.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		m := hmac.New(sha256.New, []byte(ts+"\n"+to.Secret))
+		payload["timestamp"] = ts
+		payload["sign"] = base64.StdEncoding.EncodeToString(m.Sum(nil))
+	}
+
+	body, err := postJSON(ctx, c.client, to.Address, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkBotResult(ChannelFeishu, body)
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"net/mail"
+
+	"vapiv/pkg/email"
+)
+
+// EmailChannel 把邮件放入发送队列后立即返回
+type EmailChannel struct {
+	queue *email.Queue
+}
+
+func NewEmailChannel(queue *email.Queue) *EmailChannel {
+	return &EmailChannel{queue: queue}
+}
+
+func (c *EmailChannel) Name() string {
+	return ChannelEmail
+}
+
+// Validate 允许为空。notification.Service 只会发送到已验证的账号邮箱，这里只检查格式
+func (c *EmailChannel) Validate(to Target) error {
+	if to.Address == "" {
+		return nil
+	}
+	if _, err := mail.ParseAddress(to.Address); err != nil {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+func (c *EmailChannel) Send(ctx context.Context, to Target, msg Message) error {
+	return c.queue.Enqueue(ctx, &email.Mail{
+		To:      to.Address,
+		Subject: msg.Subject,
+		Text:    msg.Body,
+		HTML:    msg.HTML,
+		// 验证码和邀请链接在过期前一直有效，不能长期留在 Redis 中
+		Sensitive: msg.Event == EventVerification || msg.Event == EventSecurity,
+	})
+}
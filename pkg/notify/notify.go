@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 用户可以按事件选择通知渠道
+const (
+	EventVerification = "verification"
+	EventLowBalance   = "low_balance"
+	EventKeyExpiry    = "key_expiry"
+	EventSecurity     = "security"
+)
+
+// Events 是用户可以设置渠道的事件。验证码总是发送到被验证的地址，不能设置
+var Events = []string{EventLowBalance, EventKeyExpiry, EventSecurity}
+
+const (
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+	ChannelWebhook  = "webhook"
+	ChannelDingTalk = "dingtalk"
+	ChannelWeCom    = "wecom"
+	ChannelFeishu   = "feishu"
+)
+
+var ErrInvalidTarget = errors.New("invalid notification target")
+
+// Message 是渲染好的通知，Body 是纯文本，HTML 只有邮件渠道使用
+type Message struct {
+	Event   string `json:"event"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	HTML    string `json:"-"`
+}
+
+// Target 是渠道的接收地址：邮箱、手机号或 webhook 地址，Secret 用于签名（可选）
+type Target struct {
+	Address string
+	Secret  string
+}
+
+// Channel 是一种外发通知方式
+type Channel interface {
+	Name() string
+	// Validate 在保存用户偏好前检查接收地址
+	Validate(to Target) error
+	Send(ctx context.Context, to Target, msg Message) error
+}
+
+func defaultClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// postJSON 发送 JSON 请求，返回 2xx 响应体
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}, header http.Header) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("POST %s: status %d", url, resp.StatusCode)
+	}
+	return body, nil
+}
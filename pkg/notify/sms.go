@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sync"
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+// SMSProvider 是短信服务商的接入接口
+type SMSProvider interface {
+	SendSMS(ctx context.Context, phone, text string) error
+}
+
+type SMSChannel struct {
+	provider SMSProvider
+}
+
+func NewSMSChannel(provider SMSProvider) *SMSChannel {
+	return &SMSChannel{provider: provider}
+}
+
+func (c *SMSChannel) Name() string {
+	return ChannelSMS
+}
+
+func (c *SMSChannel) Validate(to Target) error {
+	if !phonePattern.MatchString(to.Address) {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// Send 短信只发送正文
+func (c *SMSChannel) Send(ctx context.Context, to Target, msg Message) error {
+	return c.provider.SendSMS(ctx, to.Address, "【VAPIV】"+msg.Body)
+}
+
+type SMS struct {
+	Phone string
+	Text  string
+}
+
+// MockSMSProvider 只打印日志并保存在内存中，用于开发和测试
+type MockSMSProvider struct {
+	mu   sync.Mutex
+	sent []SMS
+}
+
+func NewMockSMSProvider() *MockSMSProvider {
+	return &MockSMSProvider{}
+}
+
+func (p *MockSMSProvider) SendSMS(ctx context.Context, phone, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, SMS{Phone: phone, Text: text})
+	slog.Info("mock sms", "phone", phone, "text", text)
+	return nil
+}
+
+func (p *MockSMSProvider) Sent() []SMS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SMS(nil), p.sent...)
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress 表示 webhook 地址指向内网、回环或链路本地地址
+var ErrForbiddenAddress = errors.New("webhook address is not a public address")
+
+// 除 netip 自带判断之外还需要拒绝的网段：运营商 NAT（阿里云元数据 100.100.100.200 在其中）、
+// 0.0.0.0/8 和基准测试网段
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicOnly 在建立连接前检查解析后的地址，域名重新解析到内网（DNS rebinding）时同样会被拒绝
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !publicAddr(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// webhookClient 不走代理、不跟随重定向，只连接公网地址，避免把服务器当作访问内网的代理
+func webhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebhookChannel 向用户配置的地址 POST JSON，
+// 配置了 Secret 时在 X-Vapiv-Signature 中带上 HMAC-SHA256(timestamp + "." + body)
+type WebhookChannel struct {
+	client *http.Client
+}
+
+// NewWebhookChannel client 为空时使用只允许连接公网地址的 client
+func NewWebhookChannel(client *http.Client) *WebhookChannel {
+	if client == nil {
+		client = webhookClient()
+	}
+	return &WebhookChannel{client: client}
+}
+
+func (c *WebhookChannel) Name() string {
+	return ChannelWebhook
+}
+
+// Validate 只能拒绝明显的内网地址，域名解析结果在连接时检查
+func (c *WebhookChannel) Validate(to Target) error {
+	u, err := url.Parse(to.Address)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidTarget
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddr(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+type webhookPayload struct {
+	Message
+	Timestamp int64 `json:"timestamp"`
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, to Target, msg Message) error {
+	if err := c.Validate(to); err != nil {
+		return err
+	}
+	payload := webhookPayload{Message: msg, Timestamp: time.Now().Unix()}
+
+	header := http.Header{}
+	if to.Secret != "" {
+		data, _ := json.Marshal(payload)
+		m := hmac.New(sha256.New, []byte(to.Secret))
+		m.Write([]byte(strconv.FormatInt(payload.Timestamp, 10) + "."))
+		m.Write(data)
+		header.Set("X-Vapiv-Signature", "sha256="+hex.EncodeToString(m.Sum(nil)))
+	}
+
+	_, err := postJSON(ctx, c.client, to.Address, payload, header)
+	return err
+}
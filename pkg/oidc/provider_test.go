@@ -0,0 +1,135 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"vapiv/pkg/oidc"
+	"vapiv/pkg/oidc/mock"
+)
+
+const redirectURL = "http://vapiv.test/auth/oidc/mock/callback"
+
+func newProvider(t *testing.T, user mock.User) (*oidc.Provider, *httptest.Server) {
+	t.Helper()
+	idp, err := mock.New("", "vapiv", "secret", user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(idp)
+	t.Cleanup(srv.Close)
+	idp.Issuer = srv.URL
+
+	p := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       srv.URL,
+		ClientID:     "vapiv",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+	}, srv.Client())
+	return p, srv
+}
+
+// authorize 访问授权地址并返回回调中的 code 和 state
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthCodeURLUsesPKCE(t *testing.T) {
+	p, _ := newProvider(t, mock.User{Subject: "u1"})
+	authURL, err := p.AuthCodeURL(context.Background(), "st", "nc", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") != oidc.Challenge("verifier") {
+		t.Errorf("PKCE parameters = %q %q", q.Get("code_challenge_method"), q.Get("code_challenge"))
+	}
+	if q.Get("state") != "st" || q.Get("nonce") != "nc" {
+		t.Errorf("state = %q, nonce = %q", q.Get("state"), q.Get("nonce"))
+	}
+	if q.Get("code_verifier") != "" {
+		t.Error("verifier must not be sent to the authorization endpoint")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	user := mock.User{Subject: "u1", Email: "dev@example.com", EmailVerified: true, Name: "Dev"}
+	p, _ := newProvider(t, user)
+	ctx := context.Background()
+
+	authURL, _ := p.AuthCodeURL(ctx, "st", "nonce-1", "verifier-1")
+	code, state := authorize(t, authURL)
+	if state != "st" {
+		t.Fatalf("state = %q", state)
+	}
+	claims, err := p.Exchange(ctx, code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != user.Subject || claims.Email != user.Email || !claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// 授权码只能使用一次
+	if _, err := p.Exchange(ctx, code, "verifier-1", "nonce-1"); !errors.Is(err, oidc.ErrTokenExchange) {
+		t.Errorf("reused code: err = %v, want ErrTokenExchange", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	p, _ := newProvider(t, mock.User{Subject: "u1"})
+	ctx := context.Background()
+
+	authURL, _ := p.AuthCodeURL(ctx, "st", "nonce-1", "verifier-1")
+	code, _ := authorize(t, authURL)
+	if _, err := p.Exchange(ctx, code, "other-verifier", "nonce-1"); !errors.Is(err, oidc.ErrTokenExchange) {
+		t.Errorf("err = %v, want ErrTokenExchange", err)
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	p, _ := newProvider(t, mock.User{Subject: "u1"})
+	ctx := context.Background()
+
+	authURL, _ := p.AuthCodeURL(ctx, "st", "nonce-1", "verifier-1")
+	code, _ := authorize(t, authURL)
+	if _, err := p.Exchange(ctx, code, "verifier-1", "nonce-2"); !errors.Is(err, oidc.ErrIDToken) {
+		t.Errorf("err = %v, want ErrIDToken", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp, err := mock.New("https://other.example.com", "vapiv", "secret", mock.User{Subject: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(idp)
+	defer srv.Close()
+
+	p := oidc.NewProvider(oidc.Config{Name: "mock", Issuer: srv.URL, ClientID: "vapiv"}, srv.Client())
+	if _, err := p.AuthCodeURL(context.Background(), "st", "nc", "v"); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Errorf("err = %v, want ErrDiscovery", err)
+	}
+}
@@ -0,0 +1,44 @@
+package response
+
+// ErrorCode 是错误目录中的一项。Code 一经发布不再修改，客户端应根据它判断错误类型，
+// 而不是 HTTP 状态码或 message
+type ErrorCode struct {
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	// locales 中的消息 key，Message 是默认语言的说明
+	Key     string `json:"message_key"`
+	Message string `json:"message"`
+}
+
+var catalog []ErrorCode
+
+func define(code string, status int, message string) ErrorCode {
+	e := ErrorCode{Code: code, Status: status, Key: "error." + code, Message: message}
+	catalog = append(catalog, e)
+	return e
+}
+
+// 错误目录，新增错误时在这里定义，不要在 handler 中直接使用状态码
+var (
+	ErrBadRequest             = define("bad_request", 400, "请求参数错误")
+	ErrUnauthorized           = define("unauthorized", 401, "未登录或登录已过期")
+	ErrInsufficientBalance    = define("insufficient_balance", 402, "余额不足")
+	ErrForbidden              = define("forbidden", 403, "没有权限")
+	ErrAdminRequired          = define("admin_required", 403, "需要管理员权限")
+	ErrImpersonationForbidden = define("impersonation_forbidden", 403, "模拟登录时不允许此操作")
+	ErrRegistrationClosed     = define("registration_closed", 403, "暂不开放注册")
+	ErrNotFound               = define("not_found", 404, "资源不存在")
+	ErrRateLimited            = define("rate_limited", 429, "请求过于频繁")
+	ErrInternal               = define("internal_error", 500, "服务器内部错误")
+	ErrUpstream               = define("upstream_error", 502, "上游服务出错")
+	ErrUpstreamUnavailable    = define("upstream_unavailable", 502, "上游服务不可用")
+	ErrUpstreamBlocked        = define("upstream_blocked", 502, "请求被上游服务拒绝，请稍后重试")
+	ErrUpstreamParse          = define("upstream_parse_failed", 502, "无法解析上游服务的响应")
+	ErrEndpointOffline        = define("endpoint_offline", 503, "接口已下线")
+	ErrUnavailable            = define("service_unavailable", 503, "服务暂不可用")
+)
+
+// Catalog 返回全部错误定义，按定义顺序排列
+func Catalog() []ErrorCode {
+	return append([]ErrorCode(nil), catalog...)
+}
@@ -0,0 +1,119 @@
+package response
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLocale 和 Locales 与 pkg/email 的邮件模板保持一致
+const DefaultLocale = "zh-CN"
+
+var Locales = []string{"zh-CN", "en"}
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// messages[locale][key]，启动时加载，文件错误会直接 panic
+var messages = loadMessages()
+
+func loadMessages() map[string]map[string]string {
+	all := map[string]map[string]string{}
+	for _, locale := range Locales {
+		data, err := localeFS.ReadFile(path.Join("locales", locale+".json"))
+		if err != nil {
+			panic(err)
+		}
+		m := map[string]string{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			panic(fmt.Sprintf("locales/%s.json: %v", locale, err))
+		}
+		all[locale] = m
+	}
+	return all
+}
+
+// T 翻译消息 key，缺少翻译时依次使用默认语言和 key 本身；有 args 时按 fmt 格式化
+func T(locale, key string, args ...interface{}) string {
+	msg, ok := messages[locale][key]
+	if !ok {
+		if msg, ok = messages[DefaultLocale][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// localize 翻译 handler 传入的消息：已知的 key 按请求语言翻译，其他内容（如参数校验的错误信息）原样返回
+func localize(c *gin.Context, msg string, args []interface{}) string {
+	if _, ok := messages[DefaultLocale][msg]; !ok {
+		return msg
+	}
+	return T(Locale(c), msg, args...)
+}
+
+// Locale 返回请求使用的语言：lang 参数优先，其次是 Accept-Language，都没有时使用默认语言
+func Locale(c *gin.Context) string {
+	if l := RequestLocale(c); l != "" {
+		return l
+	}
+	return DefaultLocale
+}
+
+// RequestLocale 与 Locale 相同，但请求没有指定支持的语言时返回空
+func RequestLocale(c *gin.Context) string {
+	if l := MatchLocale(c.Query("lang")); l != "" {
+		return l
+	}
+	return MatchLocale(c.GetHeader("Accept-Language"))
+}
+
+// MatchLocale 按 q 值从高到低匹配 Accept-Language 中第一个支持的语言，q 值相同时按出现顺序，
+// q=0 表示不接受。按主语言匹配，如 zh-TW 匹配 zh-CN、en-US 匹配 en
+func MatchLocale(header string) string {
+	type candidate struct {
+		primary string
+		q       float64
+	}
+	var list []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				var err error
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		list = append(list, candidate{primary, q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	for _, c := range list {
+		for _, l := range Locales {
+			lp, _, _ := strings.Cut(strings.ToLower(l), "-")
+			if c.primary == lp {
+				return l
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package response
+
+import (
+	"strings"
+	"testing"
+
+	"vapiv/pkg/email"
+)
+
+func TestLocalesHaveSameKeys(t *testing.T) {
+	keys := map[string]bool{}
+	for _, m := range messages {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	for _, locale := range Locales {
+		for k := range keys {
+			if messages[locale][k] == "" {
+				t.Errorf("locales/%s.json: missing %q", locale, k)
+			}
+		}
+	}
+}
+
+// 邮件模板和接口消息使用同一组语言
+func TestLocalesMatchEmail(t *testing.T) {
+	if strings.Join(Locales, ",") != strings.Join(email.Locales, ",") {
+		t.Errorf("response locales %v, email locales %v", Locales, email.Locales)
+	}
+	if DefaultLocale != email.DefaultLocale {
+		t.Errorf("response default locale %s, email default locale %s", DefaultLocale, email.DefaultLocale)
+	}
+}
+
+func TestCatalogIsTranslated(t *testing.T) {
+	for _, e := range Catalog() {
+		for _, locale := range Locales {
+			if messages[locale][e.Key] == "" {
+				t.Errorf("locales/%s.json: missing %q for error %s", locale, e.Key, e.Code)
+			}
+		}
+	}
+}
+
+func TestMatchLocale(t *testing.T) {
+	cases := map[string]string{
+		"":                      "",
+		"en-US,en;q=0.9":        "en",
+		"zh-TW":                 "zh-CN",
+		"fr-FR, en;q=0.5":       "en",
+		"ja":                    "",
+		"ZH-cn;q=0.8, en;q=0.7": "zh-CN",
+		"en;q=0.1, zh-CN;q=0.9": "zh-CN",
+		"en, zh-CN":             "en",
+		"zh;q=0, en;q=0.2":      "en",
+		"en;q=0":                "",
+		"zh-CN;q=abc, en;q=0.5": "en",
+	}
+	for header, want := range cases {
+		if got := MatchLocale(header); got != want {
+			t.Errorf("MatchLocale(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -2,16 +2,31 @@ package response
 
 import (
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Response struct {
-	Code    int         `json:"code"`
+	// 成功为 0，失败时为 HTTP 状态码，保留给旧客户端使用
+	Code int `json:"code"`
+	// 错误目录中的错误码，见 GET /catalog/errors
+	Error   string      `json:"error,omitempty"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// LegacyStatusHeader 请求带上该头（值为 1）时按旧行为返回 HTTP 200
+const LegacyStatusHeader = "X-Legacy-Status"
+
+var legacyStatus atomic.Bool
+
+// SetLegacyStatus 开启后所有错误都返回 HTTP 200，错误只体现在 body 的 code 中，
+// 用于还没有适配真实状态码的客户端
+func SetLegacyStatus(on bool) {
+	legacyStatus.Store(on)
+}
+
 func Success(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, Response{
 		Code:    0,
@@ -20,23 +35,34 @@ func Success(c *gin.Context, data interface{}) {
 	})
 }
 
-func Error(c *gin.Context, code int, message string) {
-	c.JSON(http.StatusOK, Response{
-		Code:    code,
-		Message: message,
+// Error 按错误目录写入响应。message 是 locales 中的消息 key 时按请求语言翻译，
+// 为空时使用错误码本身的说明，其他内容原样返回
+func Error(c *gin.Context, e ErrorCode, message string, args ...interface{}) {
+	if message == "" {
+		message = e.Key
+	}
+	c.JSON(status(c, e.Status), Response{
+		Code:    e.Status,
+		Error:   e.Code,
+		Message: localize(c, message, args),
 	})
 }
 
-func Unauthorized(c *gin.Context, message string) {
-	c.JSON(http.StatusUnauthorized, Response{
-		Code:    401,
-		Message: message,
-	})
+func Unauthorized(c *gin.Context, message string, args ...interface{}) {
+	Error(c, ErrUnauthorized, message, args...)
 }
 
-func BadRequest(c *gin.Context, message string) {
-	c.JSON(http.StatusBadRequest, Response{
-		Code:    400,
-		Message: message,
-	})
+func BadRequest(c *gin.Context, message string, args ...interface{}) {
+	Error(c, ErrBadRequest, message, args...)
+}
+
+// status 在兼容模式下返回 200；400 和 401 一直使用真实状态码，兼容模式下保持不变
+func status(c *gin.Context, s int) int {
+	if s == http.StatusBadRequest || s == http.StatusUnauthorized {
+		return s
+	}
+	if legacyStatus.Load() || c.GetHeader(LegacyStatusHeader) == "1" {
+		return http.StatusOK
+	}
+	return s
 }
@@ -0,0 +1,37 @@
+package token
+
+import (
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 模拟登录 token 使用 RFC 8693 的 act 声明标记实际操作人
+const (
+	ClaimActor = "act"
+	ClaimScope = "scope"
+
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// Impersonation 是模拟登录 token 中的操作人信息
+type Impersonation struct {
+	ActorID uint
+	Write   bool
+}
+
+// ParseImpersonation 从 claims 中读取 act 声明，普通 token 返回 false
+func ParseImpersonation(claims jwt.MapClaims) (*Impersonation, bool) {
+	act, ok := claims[ClaimActor].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	sub, _ := act["sub"].(string)
+	id, err := strconv.ParseUint(sub, 10, 32)
+	if err != nil || id == 0 {
+		return nil, false
+	}
+	scope, _ := claims[ClaimScope].(string)
+	return &Impersonation{ActorID: uint(id), Write: scope == ScopeWrite}, true
+}
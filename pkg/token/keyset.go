@@ -0,0 +1,247 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey      = errors.New("unknown signing key")
+	ErrUnsupportedKey  = errors.New("unsupported key type, only RSA and Ed25519 are allowed")
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrNoPEMBlockFound = errors.New("no PEM block found")
+)
+
+// 只接受这两种算法，防止 alg=none 或 HS256 混淆攻击
+var validMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+// Key 是一个带 kid 的公钥，私钥只有当前签名密钥才有
+type Key struct {
+	ID      string
+	Alg     string
+	Public  crypto.PublicKey
+	private crypto.Signer
+}
+
+// KeySet 持有当前签名密钥和所有可用于验证的公钥，
+// 轮换时把旧密钥的公钥保留在验证集合中直到旧 token 过期
+type KeySet struct {
+	signing  *Key
+	keys     map[string]*Key
+	order    []string
+	issuer   string
+	audience string
+}
+
+// NewKeySet 创建 KeySet，signer 为空时生成一个临时 Ed25519 密钥
+func NewKeySet(signer crypto.Signer, verify []crypto.PublicKey, issuer, audience string) (*KeySet, error) {
+	if signer == nil {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = priv
+	}
+
+	ks := &KeySet{keys: map[string]*Key{}, issuer: issuer, audience: audience}
+
+	signing, err := newKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	signing.private = signer
+	ks.signing = signing
+	ks.add(signing)
+
+	for _, pub := range verify {
+		k, err := newKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		ks.add(k)
+	}
+	return ks, nil
+}
+
+// LoadKeySet 从 PEM 文件加载签名私钥和额外的验证公钥
+func LoadKeySet(privateKeyFile string, publicKeyFiles []string, issuer, audience string) (*KeySet, error) {
+	var signer crypto.Signer
+	if privateKeyFile != "" {
+		data, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if signer, err = ParsePrivateKey(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", privateKeyFile, err)
+		}
+	}
+
+	verify := make([]crypto.PublicKey, 0, len(publicKeyFiles))
+	for _, file := range publicKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		verify = append(verify, pub)
+	}
+
+	return NewKeySet(signer, verify, issuer, audience)
+}
+
+func (ks *KeySet) add(k *Key) {
+	if _, ok := ks.keys[k.ID]; ok {
+		return
+	}
+	ks.keys[k.ID] = k
+	ks.order = append(ks.order, k.ID)
+}
+
+func (ks *KeySet) Issuer() string {
+	return ks.issuer
+}
+
+func (ks *KeySet) Audience() string {
+	return ks.audience
+}
+
+// Sign 使用当前签名密钥签发 token，并补充 iss/aud/iat
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"] = ks.issuer
+	claims["aud"] = ks.audience
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = time.Now().Unix()
+	}
+
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(ks.signing.Alg), claims)
+	t.Header["kid"] = ks.signing.ID
+	return t.SignedString(ks.signing.private)
+}
+
+// Parse 校验签名、算法、kid、过期时间、签发者和受众
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	t, err := jwt.ParseWithClaims(tokenString, claims, ks.keyFunc,
+		jwt.WithValidMethods(validMethods),
+		jwt.WithIssuer(ks.issuer),
+		jwt.WithAudience(ks.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != k.Alg {
+		return nil, ErrUnknownKey
+	}
+	return k.Public, nil
+}
+
+func newKey(pub crypto.PublicKey) (*Key, error) {
+	var alg string
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		alg = jwt.SigningMethodRS256.Alg()
+	case ed25519.PublicKey:
+		alg = jwt.SigningMethodEdDSA.Alg()
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	jwk, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: thumbprint(jwk), Alg: alg, Public: pub}, nil
+}
+
+// thumbprint 按 RFC 7638 计算 kid，同一把密钥在所有实例上得到相同的 kid
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMBlockFound
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMBlockFound
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,220 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func newRSA(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newKeySet(t *testing.T, signer crypto.Signer, verify ...crypto.PublicKey) *KeySet {
+	t.Helper()
+	ks, err := NewKeySet(signer, verify, "vapiv", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func sign(t *testing.T, ks *KeySet) string {
+	t.Helper()
+	signed, err := ks.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestSignSetsKidAndAlg(t *testing.T) {
+	for name, signer := range map[string]crypto.Signer{"EdDSA": newEd25519(t), "RS256": newRSA(t)} {
+		t.Run(name, func(t *testing.T) {
+			ks := newKeySet(t, signer)
+			signed := sign(t, ks)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["kid"] != ks.signing.ID {
+				t.Errorf("kid = %v, want %s", parsed.Header["kid"], ks.signing.ID)
+			}
+			if parsed.Method.Alg() != name {
+				t.Errorf("alg = %s, want %s", parsed.Method.Alg(), name)
+			}
+			claims, err := ks.Parse(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims["iss"] != "vapiv" || claims["aud"] != "api" {
+				t.Errorf("iss = %v, aud = %v", claims["iss"], claims["aud"])
+			}
+		})
+	}
+}
+
+// 同一把密钥在不同实例上得到相同的 kid，不同密钥的 kid 不同
+func TestKidIsThumbprint(t *testing.T) {
+	priv := newEd25519(t)
+	a, b := newKeySet(t, priv), newKeySet(t, priv)
+	if a.signing.ID != b.signing.ID {
+		t.Errorf("kid differs between instances: %s, %s", a.signing.ID, b.signing.ID)
+	}
+	if other := newKeySet(t, newEd25519(t)); other.signing.ID == a.signing.ID {
+		t.Error("different keys share a kid")
+	}
+}
+
+func TestRotationAcceptsOldKeys(t *testing.T) {
+	oldKey := newRSA(t)
+	oldToken := sign(t, newKeySet(t, oldKey))
+
+	rotated := newKeySet(t, newEd25519(t), oldKey.Public())
+	if _, err := rotated.Parse(oldToken); err != nil {
+		t.Errorf("token signed with the old key: %v", err)
+	}
+	if _, err := rotated.Parse(sign(t, rotated)); err != nil {
+		t.Errorf("token signed with the new key: %v", err)
+	}
+	if n := len(rotated.JWKS().Keys); n != 2 {
+		t.Errorf("JWKS has %d keys, want 2", n)
+	}
+
+	// 旧公钥移除后旧 token 失效
+	if _, err := newKeySet(t, rotated.signing.private).Parse(oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseRejectsOtherAlgorithms(t *testing.T) {
+	ks := newKeySet(t, newRSA(t))
+	kid := ks.signing.ID
+
+	// HS256 使用公钥内容作为 HMAC 密钥的混淆攻击
+	pubDER, _ := x509.MarshalPKIXPublicKey(ks.signing.Public)
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	hs.Header["kid"] = kid
+	hsToken, _ := hs.SignedString(pubDER)
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims())
+	none.Header["kid"] = kid
+	noneToken, _ := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	// kid 指向 RSA 密钥，但声明为 EdDSA
+	ed := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	ed.Header["kid"] = kid
+	edToken, _ := ed.SignedString(newEd25519(t))
+
+	// 使用 RSA 密钥但算法是 RS512
+	rs := jwt.NewWithClaims(jwt.SigningMethodRS512, testClaims())
+	rs.Header["kid"] = kid
+	rsToken, _ := rs.SignedString(ks.signing.private)
+
+	for name, tok := range map[string]string{"HS256": hsToken, "none": noneToken, "EdDSA": edToken, "RS512": rsToken} {
+		if _, err := ks.Parse(tok); err == nil {
+			t.Errorf("%s token accepted", name)
+		}
+	}
+}
+
+func TestParseChecksClaims(t *testing.T) {
+	priv := newEd25519(t)
+	ks := newKeySet(t, priv)
+	cases := map[string]jwt.MapClaims{
+		"expired": {"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()},
+		"no exp":  {"sub": "1"},
+	}
+	for name, c := range cases {
+		signed, err := ks.Sign(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ks.Parse(signed); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	// 同一把密钥，签发者或受众不同
+	for _, other := range []*KeySet{
+		mustKeySet(t, priv, "other", "api"),
+		mustKeySet(t, priv, "vapiv", "other"),
+	} {
+		if _, err := ks.Parse(sign(t, other)); err == nil {
+			t.Errorf("token from iss=%s aud=%s accepted", other.issuer, other.audience)
+		}
+	}
+
+	foreign := sign(t, newKeySet(t, newEd25519(t)))
+	if _, err := ks.Parse(foreign); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown kid: err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func mustKeySet(t *testing.T, signer crypto.Signer, issuer, audience string) *KeySet {
+	t.Helper()
+	ks, err := NewKeySet(signer, nil, issuer, audience)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	priv := newEd25519(t)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	privFile := filepath.Join(dir, "jwt.pem")
+	os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+
+	old := newRSA(t)
+	pubDER, _ := x509.MarshalPKIXPublicKey(old.Public())
+	pubFile := filepath.Join(dir, "old.pub")
+	os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)
+
+	ks, err := LoadKeySet(privFile, []string{pubFile}, "vapiv", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := newKeySet(t, priv).signing.ID; ks.signing.ID != want {
+		t.Errorf("signing kid = %s, want %s", ks.signing.ID, want)
+	}
+	if len(ks.keys) != 2 {
+		t.Errorf("keys = %d, want 2", len(ks.keys))
+	}
+
+	badFile := filepath.Join(dir, "ec.pem")
+	os.WriteFile(badFile, []byte("not a pem"), 0o600)
+	if _, err := LoadKeySet(badFile, nil, "vapiv", "api"); !errors.Is(err, ErrNoPEMBlockFound) {
+		t.Errorf("err = %v, want ErrNoPEMBlockFound", err)
+	}
+}
@@ -29,18 +29,57 @@ api.interceptors.response.use(
   }
 );
 
+interface Challenge {
+  id: string;
+  prefix: string;
+  difficulty: number;
+}
+
+// 找到 nonce 使 sha256(prefix + nonce) 至少有 difficulty 个前导零比特
+async function solveChallenge({ prefix, difficulty }: Challenge): Promise<string> {
+  const enc = new TextEncoder();
+  for (let nonce = 0; ; nonce++) {
+    const buf = new Uint8Array(await crypto.subtle.digest('SHA-256', enc.encode(prefix + nonce)));
+    let bits = 0;
+    for (const b of buf) {
+      if (b === 0) {
+        bits += 8;
+        continue;
+      }
+      bits += Math.clz32(b) - 24;
+      break;
+    }
+    if (bits >= difficulty) return String(nonce);
+  }
+}
+
 // Auth API
 export const authApi = {
   login: (username: string, password: string) =>
     api.post('/auth/login', { username, password }),
   register: (username: string, email: string, password: string, code: string) =>
     api.post('/auth/register', { username, email, password, code }),
-  sendCode: (email: string, purpose: 'register' | 'reset') =>
-    api.post('/auth/send-code', { email, purpose }),
+  // 发送验证码前需要完成工作量证明，见 GET /auth/challenge
+  sendCode: async (email: string, purpose: 'register' | 'reset') => {
+    const res = await api.get<Challenge, { data: Challenge }>('/auth/challenge');
+    const nonce = await solveChallenge(res.data);
+    return api.post('/auth/send-code', { email, purpose, challenge: `${res.data.id}.${nonce}` });
+  },
   resetPassword: (email: string, code: string, new_password: string) =>
     api.post('/auth/reset-password', { email, code, new_password }),
 };
 
+// 已登录用户的账号设置
+export const accountApi = {
+  // 与 sendCode 一样需要先完成工作量证明
+  sendChangeEmailCode: async (new_email: string) => {
+    const res = await api.get<Challenge, { data: Challenge }>('/auth/challenge');
+    const nonce = await solveChallenge(res.data);
+    return api.post('/user/email/send-code', { new_email, challenge: `${res.data.id}.${nonce}` });
+  },
+  changeEmail: (new_email: string, code: string) => api.put('/user/email', { new_email, code }),
+};
+
 // API Key management
 export const apiKeyApi = {
   list: () => api.get('/user/apikeys'),